@@ -1,79 +1,234 @@
 package main
 
 import (
+	"backend/internal/auth"
+	"backend/internal/config"
 	"backend/internal/db"
+	"backend/internal/events"
 	"backend/internal/handlers"
-	"log"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/notify"
+	"backend/internal/realtime"
+	"backend/internal/repository/mysql"
+	"backend/internal/requestid"
+	"backend/internal/services"
+	"backend/internal/storage"
+	"backend/internal/tracing"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
+	// SIGTERM (Cloud Run の停止) や Ctrl+C で ctx が切れる
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 設定を読むまでは既定の形式でログを書く（設定エラーも1行の構造化ログになる）
+	slog.SetDefault(logging.New(os.Stdout, config.Default().Log, ""))
+
+	// 0. 設定の読み込みと検証（問題はまとめて表示して終了する）
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("config load failed", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("config invalid", err)
+	}
+
+	// 以降のログは Cloud Logging の構造化ログ (LOG_LEVEL / LOG_FORMAT)。
+	// 標準の log パッケージやライブラリのログもこのロガーを通る
+	logger := logging.New(os.Stdout, cfg.Log, cfg.AI.ProjectID)
+	slog.SetDefault(logger)
+
+	// トレースの送り先 (TRACING_EXPORTER)。DB や AI のスパンもここに送られる
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		fatal("tracing setup failed", err)
+	}
+
 	// 1. データベースの初期化
-	db.InitDB()
-
-	// 2. Ginルーターの初期化
-	r := gin.Default()
-
-	// 3. CORSの設定 (Vercelとローカル両方を許可)
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{
-		"http://localhost:5173",
-		"https://hackathon-frontend-jet.vercel.app",
-	}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	r.Use(cors.New(config))
-
-	// 4. APIルートのグループ化
-	api := r.Group("/api")
-	{
-		// --- ヘルスチェック ---
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{"status": "ok"})
-		})
-
-		// --- 商品関連 (Products) ---
-		api.GET("/products", handlers.GetProducts)
-		api.GET("/products/:id", handlers.GetProductByID)
-		api.POST("/products", handlers.CreateProduct)
-		api.POST("/products/:id/purchase", handlers.PurchaseProduct) // 購入処理
-
-		// --- ユーザー関連 ---
-		api.GET("/users/:uid", handlers.GetUserByID)
-		api.GET("/users/:uid/profile", handlers.GetUserProfile)
-		api.POST("/users/sync", handlers.SyncUser)
-
-		// --- いいね・DM関連 ---
-		api.POST("/likes/toggle", handlers.ToggleLike)
-		api.GET("/likes/status", handlers.CheckLikeStatus)
-		api.POST("/messages", handlers.SendMessage)
-		api.GET("/messages", handlers.GetChatHistory)
-
-		// --- Gemini AI連携関連 (ここをReactのURLに合わせる) ---
-		// Reactの Sell.tsx が axios.post("/api/ai/description") を叩くので合わせます
-		api.POST("/ai/description", handlers.GenerateAIDescription)
-		api.POST("/ai/suggest-price", handlers.SuggestAIPrice)
-
-		api.GET("/debug-env", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"project_id": os.Getenv("GCP_PROJECT_ID"),
-				"port":       os.Getenv("PORT"),
-				"instance":   os.Getenv("INSTANCE_CONNECTION_NAME"),
-			})
-		})
-	}
-
-	// 5. サーバー起動
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("🚀 Server is running on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("サーバーの起動に失敗しました:", err)
+	db.InitDB(cfg.DB)
+	metrics.RegisterDB(db.DB, cfg.DB.Name)
+	// db.auto_migrate (DB_AUTO_MIGRATE) なら起動時にスキーマを最新にする
+	if cfg.DB.AutoMigrate {
+		if err := db.MigrateUp(ctx); err != nil {
+			fatal("migration failed", err)
+		}
+	}
+
+	// LLMプロバイダの選択 (LLM_PROVIDER / LLM_MODEL)
+	llm, err := services.NewLLM(ctx, cfg.AI)
+	if err != nil {
+		fatal("llm setup failed", err)
+	}
+
+	// 画像の保存先 (STORAGE_BACKEND)
+	store, err := storage.New(ctx, cfg.Storage)
+	if err != nil {
+		fatal("storage setup failed", err)
+	}
+
+	repos := mysql.NewRepositories(db.DB)
+
+	// 通知の送り先 (SMTP_ADDR でメール、VAPID_PRIVATE_KEY で Web Push を有効にする)
+	notifier, err := notify.NewFromConfig(repos, cfg.Notify, logger)
+	if err != nil {
+		fatal("notify setup failed", err)
+	}
+	bus := events.NewBus()
+	bus.Subscribe(notifier.Handle)
+
+	// ハンドラーに依存関係を注入する
+	hub := realtime.NewHub(realtime.NewLocalPubSub())
+	h := handlers.New(handlers.Deps{
+		Repositories:  repos,
+		AI:            services.NewAI(llm, logger),
+		Storage:       store,
+		Hub:           hub,
+		OfferTTL:      cfg.Offer.TTL,
+		PaymentTTL:    cfg.Order.PaymentTTL,
+		Events:        bus,
+		PushPublicKey: notifier.PushPublicKey(),
+		Config:        cfg,
+		Logger:        logger,
+	})
+	// 期限を過ぎた価格提示と支払われない注文を定期的に締め切る（停止の合図で止まる）
+	var expiry sync.WaitGroup
+	expiry.Add(2)
+	go func() {
+		defer expiry.Done()
+		h.RunOfferExpiry(ctx, time.Minute)
+	}()
+	go func() {
+		defer expiry.Done()
+		h.RunPaymentExpiry(ctx, time.Minute)
+	}()
+
+	// 2. Ginルーターの初期化（アクセスログと panic の記録は gin 既定のものではなく slog で書く）
+	r := gin.New()
+	// リクエストごとのスパン（フロントの traceparent を引き継ぐ）。スクレイプやヘルスチェックは除く
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return c.FullPath() != "/metrics" && c.FullPath() != "/api/health"
+	})))
+	// 404 などルートに当たらないリクエストにもIDを付けて、アクセスログと突き合わせられるようにする
+	r.Use(requestid.Middleware(), logging.AccessLog(logger), logging.Recovery(logger))
+	r.Use(metrics.Middleware())
+
+	// 3. CORSの設定 (cors.allow_origins / CORS_ALLOW_ORIGINS)
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = cfg.CORS.AllowOrigins
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	// traceparent / tracestate はフロントからトレースを引き継ぐための W3C Trace Context のヘッダー
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", requestid.Header, "traceparent", "tracestate"}
+	corsConfig.ExposeHeaders = []string{requestid.Header} // 問い合わせ時にリクエストIDを確認できるように
+	r.Use(cors.New(corsConfig))
+
+	// 4. Firebase IDトークン検証の準備
+	verifier := newVerifier(cfg.Firebase)
+
+	// 5. APIルートの登録（/admin と /metrics は ADMIN_TOKEN を設定したときだけ）
+	h.RegisterRoutes(r, verifier, handlers.RouteTimeouts(cfg.AI.Timeout))
+	h.RegisterAdminRoutes(r)
+
+	// 6. サーバー起動
+	srv := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	// Shutdown は WebSocket（ハイジャック済みの接続）を待たないので、購読を閉じて再接続を促す
+	srv.RegisterOnShutdown(hub.Close)
+
+	serveErr := make(chan error, 2)
+	go func() {
+		logger.Info("server started", "port", cfg.Server.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// 管理用ポートの /metrics（METRICS_ADDR を指定したときだけ）
+	var metricsSrv *http.Server
+	if cfg.Server.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{Addr: cfg.Server.MetricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			logger.Info("metrics server started", "addr", cfg.Server.MetricsAddr)
+			serveErr <- metricsSrv.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		fatal("server failed", err)
+	case <-ctx.Done():
+	}
+	stop() // 2回目の Ctrl+C ではすぐに終了できるように
+
+	// 7. 停止処理: 新しい接続を断り、処理中の購入やAI呼び出しが終わるのを待ってから DB を閉じる
+	logger.Info("shutting down", "timeout", cfg.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("in-flight requests did not finish", "error", err)
+	}
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
+	expiry.Wait()
+	if err := bus.Wait(shutdownCtx); err != nil {
+		logger.Warn("pending notifications did not finish", "error", err)
+	}
+	if c, ok := llm.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			logger.Warn("llm close failed", "error", err)
+		}
+	}
+	if err := db.DB.Close(); err != nil {
+		logger.Warn("database close failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("trace flush failed", "error", err)
+	}
+	logger.Info("shutdown complete")
+}
+
+// fatal はエラーを ERROR で残して終了する
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// newVerifier はIDトークン検証器を作る。
+// firebase.jwks_file を指定するとローカルの鍵セットで検証する（オフライン検証用）
+func newVerifier(cfg config.Firebase) *auth.Verifier {
+	if cfg.JWKSFile != "" {
+		data, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			fatal("jwks file read failed", err)
+		}
+		keys, err := auth.ParseJWKS(data)
+		if err != nil {
+			fatal("jwks file parse failed", err)
+		}
+		return auth.NewVerifier(cfg.ProjectID, keys)
+	}
+
+	jwksURL := cfg.JWKSURL
+	if jwksURL == "" {
+		jwksURL = auth.FirebaseJWKSURL
 	}
+	return auth.NewVerifier(cfg.ProjectID, auth.NewJWKSCache(jwksURL))
 }
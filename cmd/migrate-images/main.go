@@ -0,0 +1,125 @@
+// migrate-images は products.image_url に残っている Base64 画像をストレージに移し、
+// image_key に参照を入れて image_url を空にする一回限りのコマンド。
+// 途中で止めても、もう一度実行すれば残りから再開する
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/internal/logging"
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	batch := flag.Int("batch", 50, "1回に読み込む商品数")
+	dryRun := flag.Bool("dry-run", false, "移行対象を表示するだけで書き込まない")
+	flag.Parse()
+
+	ctx := context.Background()
+	// 設定を読むまでは既定の形式でログを書く（API サーバーと同じ構造化ログ）
+	slog.SetDefault(logging.New(os.Stdout, config.Default().Log, ""))
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("config load failed", err)
+	}
+	if err := errors.Join(cfg.DB.Validate(), cfg.Storage.Validate()); err != nil {
+		fatal("config invalid", err)
+	}
+	logger := logging.New(os.Stdout, cfg.Log, "")
+	slog.SetDefault(logger)
+	db.InitDB(cfg.DB)
+	defer db.DB.Close()
+
+	store, err := storage.New(ctx, cfg.Storage)
+	if err != nil {
+		fatal("storage setup failed", err)
+	}
+
+	var moved, failed int
+	lastID := 0
+	for {
+		// Base64 の行だけを id 順に少しずつ読む（1行が数MBになることがあるため）
+		rows, err := db.DB.QueryContext(ctx,
+			"SELECT id, seller_id, image_url FROM products WHERE id > ? AND image_url LIKE 'data:%' ORDER BY id LIMIT ?",
+			lastID, *batch)
+		if err != nil {
+			fatal("select products failed", err)
+		}
+		type row struct {
+			id       int
+			sellerID string
+			dataURL  string
+		}
+		var targets []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.sellerID, &r.dataURL); err != nil {
+				fatal("scan product failed", err)
+			}
+			targets = append(targets, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			fatal("select products failed", err)
+		}
+		if len(targets) == 0 {
+			break
+		}
+
+		for _, r := range targets {
+			lastID = r.id
+			key, err := migrate(ctx, store, r.id, r.sellerID, r.dataURL, *dryRun)
+			if err != nil {
+				logger.Warn("product skipped", "product_id", r.id, "error", err)
+				failed++
+				continue
+			}
+			logger.Info("product migrated", "product_id", r.id, "image_key", key)
+			moved++
+		}
+	}
+	logger.Info("done", "moved", moved, "failed", failed, "dry_run", *dryRun)
+}
+
+// fatal はエラーを ERROR で残して終了する
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func migrate(ctx context.Context, store storage.Storage, id int, sellerID, dataURL string, dryRun bool) (string, error) {
+	data, err := storage.DecodeDataURL(dataURL)
+	if err != nil {
+		return "", err
+	}
+	contentType, ext, err := storage.DetectImage(data)
+	if err != nil {
+		return "", err
+	}
+	key := storage.NewImageKey(storage.ProductImagePrefix(sellerID), ext)
+	if dryRun {
+		return key, nil
+	}
+
+	if err := store.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return "", err
+	}
+	// 移行中に他の処理で画像が差し替えられていたら上書きしない
+	res, err := db.DB.ExecContext(ctx,
+		"UPDATE products SET image_key = ?, image_url = '' WHERE id = ? AND image_url = ?", key, id, dataURL)
+	if err != nil {
+		store.Delete(ctx, key)
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		store.Delete(ctx, key)
+		return "", errors.New("row changed during migration")
+	}
+	return key, nil
+}
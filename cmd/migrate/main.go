@@ -0,0 +1,103 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/internal/logging"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up              未適用のマイグレーションをすべて適用する
+  down [n]        適用済みのマイグレーションを n 個（省略時は1個）巻き戻す
+  to <version>    指定バージョンまで適用または巻き戻す（0 で全て巻き戻す）
+  status          各マイグレーションの適用状況を表示する
+
+接続先は API サーバーと同じ設定 (CONFIG_FILE か MYSQL_USER, MYSQL_HOST などの環境変数) で指定します。`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// 設定を読むまでは既定の形式でログを書く（API サーバーと同じ構造化ログ）
+	slog.SetDefault(logging.New(os.Stdout, config.Default().Log, ""))
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("config load failed", err)
+	}
+	if err := cfg.DB.Validate(); err != nil {
+		fatal("db config invalid", err)
+	}
+	slog.SetDefault(logging.New(os.Stdout, cfg.Log, ""))
+	db.InitDB(cfg.DB)
+	defer db.DB.Close()
+
+	m, err := db.NewMigrator(db.DB)
+	if err != nil {
+		fatal("migrations load failed", err)
+	}
+
+	ctx := context.Background()
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				fatal("invalid argument", fmt.Errorf("down の引数は1以上の数値で指定してください: %q", args[0]))
+			}
+		}
+		err = m.Down(ctx, steps)
+	case "to":
+		if len(args) != 1 {
+			fatal("invalid argument", errors.New("to にはバージョンを指定してください"))
+		}
+		version, convErr := strconv.Atoi(args[0])
+		if convErr != nil {
+			fatal("invalid argument", fmt.Errorf("バージョンは数値で指定してください: %q", args[0]))
+		}
+		err = m.To(ctx, version)
+	case "status":
+		err = printStatus(ctx, m)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal("migrate "+os.Args[1]+" failed", err)
+	}
+}
+
+// fatal はエラーを ERROR で残して終了する
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func printStatus(ctx context.Context, m *db.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
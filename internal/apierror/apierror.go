@@ -0,0 +1,120 @@
+// Package apierror はAPIのエラーレスポンスを1つの形式にそろえる。
+// ハンドラーは Abort でエラーを積むだけにして、レスポンスは Middleware が書く。
+// 内部のエラー（SQLのエラーなど）はログにだけ出し、クライアントには返さない
+package apierror
+
+import (
+	"backend/internal/requestid"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error はクライアントに返すエラー。Code は言語によらず変わらない識別子
+type Error struct {
+	Status int
+	Code   string
+
+	messages map[string]string // 言語 → メッセージ（fmt の書式）
+	args     []any
+	cause    error
+	fields   []FieldError
+}
+
+func define(status int, code, ja, en string) *Error {
+	return &Error{Status: status, Code: code, messages: map[string]string{"ja": ja, "en": en}}
+}
+
+// With はメッセージの書式に埋め込む値を付けたコピーを返す
+func (e *Error) With(args ...any) *Error {
+	cp := *e
+	cp.args = args
+	return &cp
+}
+
+// Wrap は原因となった内部エラーを付けたコピーを返す。原因はログにだけ出る
+func (e *Error) Wrap(cause error) *Error {
+	cp := *e
+	cp.cause = cause
+	return &cp
+}
+
+// Message は lang のメッセージを返す。対応していない言語なら日本語
+func (e *Error) Message(lang string) string {
+	format, ok := e.messages[lang]
+	if !ok {
+		format = e.messages[defaultLang]
+	}
+	if len(e.args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, e.args...)
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Code + ": " + e.cause.Error()
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error { return e.cause }
+
+// Is は Code が同じなら同じエラーとみなす（With / Wrap したコピーも一致する）
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Abort はエラーを積んで後続のハンドラーを止める。レスポンスは Middleware が書く
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Middleware は積まれたエラーのうち最後のものをレスポンスにする。
+// リクエストの上限時間を過ぎていたら Timeout、*Error 以外のエラーは Internal として扱う。
+// 原因のあるエラーとサーバー側のエラーは logger に残す（行にはリクエストIDが付く）
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var e *Error
+		if c.Request.Context().Err() == context.DeadlineExceeded {
+			e = Timeout.Wrap(err)
+		} else if !errors.As(err, &e) {
+			e = Internal.Wrap(err)
+		}
+		if e.cause != nil || e.Status >= http.StatusInternalServerError {
+			lvl := slog.LevelWarn
+			if e.Status >= http.StatusInternalServerError {
+				lvl = slog.LevelError
+			}
+			logger.LogAttrs(c.Request.Context(), lvl, "request failed",
+				slog.String("code", e.Code),
+				slog.Int("status", e.Status),
+				slog.String("route", c.FullPath()),
+				slog.Any("error", err),
+			)
+		}
+
+		lang := language(c.GetHeader("Accept-Language"))
+		body := gin.H{
+			"error":      e.Message(lang),
+			"code":       e.Code,
+			"request_id": requestid.FromContext(c.Request.Context()),
+		}
+		if len(e.fields) > 0 {
+			body["fields"] = fieldsBody(e.fields, lang)
+		}
+		c.JSON(e.Status, body)
+	}
+}
@@ -0,0 +1,74 @@
+package apierror
+
+import "net/http"
+
+// 共通
+var (
+	Internal     = define(http.StatusInternalServerError, "internal", "サーバーでエラーが発生しました。時間をおいて再度お試しください", "An internal error occurred. Please try again later")
+	InvalidJSON  = define(http.StatusBadRequest, "invalid_json", "リクエスト形式が正しくありません", "The request body is malformed")
+	InvalidParam = define(http.StatusBadRequest, "invalid_param", "%s の指定が正しくありません", "Invalid value for %s")
+	Timeout      = define(http.StatusGatewayTimeout, "timeout", "処理に時間がかかりすぎたため中断しました。時間をおいて再度お試しください", "The request took too long and was cancelled. Please try again later")
+)
+
+// 認証
+var (
+	InvalidAuthHeader = define(http.StatusUnauthorized, "invalid_auth_header", "Authorizationヘッダーの形式が不正です", "The Authorization header is malformed")
+	InvalidToken      = define(http.StatusUnauthorized, "invalid_token", "認証トークンが無効です", "The ID token is invalid or expired")
+	LoginRequired     = define(http.StatusUnauthorized, "login_required", "ログインが必要です", "Login required")
+	InvalidAdminToken = define(http.StatusUnauthorized, "invalid_admin_token", "管理用トークンが正しくありません", "The admin token is missing or invalid")
+)
+
+// 商品
+var (
+	ProductNotFound     = define(http.StatusNotFound, "product_not_found", "商品が見つかりませんでした", "Product not found")
+	NotOwner            = define(http.StatusForbidden, "not_owner", "出品者本人しか変更できません", "Only the seller can modify this product")
+	ProductSold         = define(http.StatusConflict, "product_sold", "売れた商品は変更できません", "A sold product cannot be modified")
+	VersionConflict     = define(http.StatusConflict, "version_conflict", "他の画面で商品が更新されています。最新の内容を読み込んでからやり直してください", "The product was updated elsewhere. Reload it and try again")
+	SearchQueryRequired = define(http.StatusBadRequest, "search_query_required", "検索語 q を指定してください", "The search query q is required")
+)
+
+// 購入・注文
+var (
+	SelfPurchase      = define(http.StatusBadRequest, "self_purchase", "自分の商品は購入できません", "You cannot buy your own product")
+	SoldOut           = define(http.StatusConflict, "sold_out", "この商品はすでに売り切れです", "This product is already sold")
+	ProductWithdrawn  = define(http.StatusConflict, "product_withdrawn", "この商品は現在出品されていません", "This product is not currently listed")
+	OrderNotFound     = define(http.StatusNotFound, "order_not_found", "注文が見つかりませんでした", "Order not found")
+	InvalidTransition = define(http.StatusConflict, "invalid_transition", "この注文のステータスは変更できません", "The order cannot move to that status")
+	OrderConflict     = define(http.StatusConflict, "order_conflict", "注文が他の操作で更新されました", "The order was updated by another operation")
+	ProductReserved   = define(http.StatusConflict, "product_reserved", "この商品は価格交渉が成立した別の購入者のために確保されています", "This product is reserved for another buyer after an accepted offer")
+)
+
+// 価格交渉
+var (
+	OfferNotFound  = define(http.StatusNotFound, "offer_not_found", "価格提示が見つかりませんでした", "Offer not found")
+	SelfOffer      = define(http.StatusBadRequest, "self_offer", "自分の商品には価格を提示できません", "You cannot make an offer on your own product")
+	OfferPending   = define(http.StatusConflict, "offer_pending", "この商品にはすでに返答待ちの価格提示があります", "You already have a pending offer on this product")
+	OfferClosed    = define(http.StatusConflict, "offer_closed", "この価格提示は期限切れか、すでに返答済みです", "This offer has expired or has already been answered")
+	OfferForbidden = define(http.StatusForbidden, "offer_forbidden", "この価格提示に対してその操作はできません", "You cannot perform that action on this offer")
+)
+
+// 評価
+var (
+	OrderNotCompleted = define(http.StatusConflict, "order_not_completed", "取引が完了していないため評価できません", "The order must be completed before it can be reviewed")
+	AlreadyReviewed   = define(http.StatusConflict, "already_reviewed", "この取引はすでに評価済みです", "You have already reviewed this order")
+)
+
+// ユーザー
+var (
+	UserNotFound = define(http.StatusNotFound, "user_not_found", "ユーザーが見つかりませんでした", "User not found")
+)
+
+// 画像
+var (
+	ImageRequired    = define(http.StatusBadRequest, "image_required", "image フィールドに画像を指定してください", "Attach an image in the image field")
+	ImageUnreadable  = define(http.StatusBadRequest, "image_unreadable", "画像を読み込めませんでした", "The image could not be read")
+	ImageTooLarge    = define(http.StatusRequestEntityTooLarge, "image_too_large", "画像サイズが大きすぎます（5MBまで）", "The image is too large (max 5MB)")
+	UnsupportedImage = define(http.StatusUnsupportedMediaType, "unsupported_image", "JPEG / PNG / WebP / GIF の画像を指定してください", "Use a JPEG, PNG, WebP or GIF image")
+	ImageNotFound    = define(http.StatusNotFound, "image_not_found", "画像が見つかりませんでした", "Image not found")
+)
+
+// AI
+var (
+	AIFailed            = define(http.StatusBadGateway, "ai_failed", "AIの呼び出しに失敗しました。時間をおいて再度お試しください", "The AI request failed. Please try again later")
+	AIMalformedResponse = define(http.StatusBadGateway, "ai_malformed_response", "AIの査定結果を読み取れませんでした。時間をおいて再度お試しください", "The AI returned an unreadable result. Please try again later")
+)
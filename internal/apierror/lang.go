@@ -0,0 +1,28 @@
+package apierror
+
+import (
+	"strconv"
+	"strings"
+)
+
+const defaultLang = "ja"
+
+// language は Accept-Language から対応している言語（ja / en）を選ぶ。
+// q値が一番大きいものを使い、どれも対応していなければ日本語
+func language(header string) string {
+	best, bestQ := defaultLang, -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if (base == "ja" || base == "en") && q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
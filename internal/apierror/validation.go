@@ -0,0 +1,103 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationFailed は入力チェックの失敗。どの項目がなぜ駄目だったかは fields に入る
+var ValidationFailed = define(http.StatusBadRequest, "validation_failed", "入力内容に誤りがあります", "Some fields are invalid")
+
+// FieldError は1項目分の入力エラー。Rule は validator のタグ名か、下の独自ルール
+type FieldError struct {
+	Field string
+	Rule  string
+	Param string
+}
+
+// validator のタグ以外に、ハンドラー側で参照先などを確かめたときのルール
+const (
+	RuleNotFound = "not_found" // 参照先の商品・ユーザーが存在しない
+	RuleSelf     = "self"      // 自分自身は指定できない
+	RuleNotOwned = "not_owned" // 自分のアップロードした画像ではない
+	RuleImage    = "image"     // 対応している形式の画像として読めない
+)
+
+// 文字列の min / max は文字数の意味になるので、ルール名を分けて返す
+var fieldMessages = map[string]map[string]string{
+	"required":     {"ja": "必須です", "en": "is required"},
+	"notblank":     {"ja": "空白以外の文字を入力してください", "en": "must not be blank"},
+	"email":        {"ja": "メールアドレスの形式で指定してください", "en": "must be a valid email address"},
+	"min":          {"ja": "%s以上で指定してください", "en": "must be at least %s"},
+	"max":          {"ja": "%s以下で指定してください", "en": "must be at most %s"},
+	"min_length":   {"ja": "%s文字以上で入力してください", "en": "must be at least %s characters"},
+	"max_length":   {"ja": "%s文字以内で入力してください", "en": "must be at most %s characters"},
+	"oneof":        {"ja": "%s のいずれかを指定してください", "en": "must be one of: %s"},
+	"url":          {"ja": "URLの形式で指定してください", "en": "must be a valid URL"},
+	"startswith":   {"ja": "%s で始まる値を指定してください", "en": "must start with %s"},
+	"pushendpoint": {"ja": "対応しているプッシュサービスのURLではありません", "en": "must be a URL of a supported push service"},
+	RuleNotFound:   {"ja": "存在しません", "en": "does not exist"},
+	RuleSelf:       {"ja": "自分自身は指定できません", "en": "cannot be yourself"},
+	RuleImage:      {"ja": "JPEG / PNG / WebP / GIF の画像を data URL で指定してください", "en": "must be a JPEG, PNG, WebP or GIF image as a data URL"},
+	RuleNotOwned:   {"ja": "自分でアップロードした画像を指定してください", "en": "must be an image you uploaded"},
+	"":             {"ja": "値が正しくありません", "en": "is invalid"},
+}
+
+// WithFields は項目ごとのエラーを付けたコピーを返す
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	cp := *e
+	cp.fields = fields
+	return &cp
+}
+
+// Invalid は1項目だけの入力エラーを作る
+func Invalid(field, rule string) *Error {
+	return ValidationFailed.WithFields(FieldError{Field: field, Rule: rule})
+}
+
+// FromBinding は ShouldBindJSON のエラーを変換する。
+// validator のエラーなら項目ごとのエラーに、JSON自体が壊れていれば InvalidJSON にする
+func FromBinding(err error) *Error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return InvalidJSON.Wrap(err)
+	}
+	fields := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		rule := fe.Tag()
+		if (rule == "min" || rule == "max") && fe.Kind() == reflect.String {
+			rule += "_length"
+		}
+		fields = append(fields, FieldError{Field: fe.Field(), Rule: rule, Param: fe.Param()})
+	}
+	return ValidationFailed.WithFields(fields...)
+}
+
+func (f FieldError) message(lang string) string {
+	messages, ok := fieldMessages[f.Rule]
+	if !ok {
+		// 汎用の文言にはパラメータの入る場所が無い
+		messages, f.Param = fieldMessages[""], ""
+	}
+	format, ok := messages[lang]
+	if !ok {
+		format = messages[defaultLang]
+	}
+	if f.Param == "" {
+		return format
+	}
+	return fmt.Sprintf(format, f.Param)
+}
+
+// fieldsBody はレスポンスの fields を作る
+func fieldsBody(fields []FieldError, lang string) []map[string]string {
+	out := make([]map[string]string, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, map[string]string{"field": f.Field, "code": f.Rule, "message": f.message(lang)})
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"backend/internal/apierror"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdminToken は Authorization: Bearer が運用向けの管理トークンと一致しないリクエストを401で弾く。
+// Firebase のIDトークンとは別物なので、Middleware を通さないルートに付ける
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(raw), []byte(token)) != 1 {
+			apierror.Abort(c, apierror.InvalidAdminToken)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FirebaseJWKSURL はFirebase IDトークンの署名鍵(JWKS)の公開URL
+const FirebaseJWKSURL = "https://www.googleapis.com/service_accounts/v1/jwk/securetoken@system.gserviceaccount.com"
+
+// KeySource はkidに対応するRSA公開鍵を返す
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// StaticKeySet は固定の鍵セット。ローカル生成した鍵でのオフライン検証に使う
+type StaticKeySet map[string]*rsa.PublicKey
+
+func (s StaticKeySet) Key(_ context.Context, kid string) (*rsa.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// minRefetchInterval は未知のkidで取り直す間隔の下限。
+// でたらめなkidのトークンを大量に送られても、JWKSエンドポイントを叩くのはこの間隔に1回まで
+const minRefetchInterval = 30 * time.Second
+
+// JWKSCache はJWKSエンドポイントから取得した鍵を Cache-Control の max-age の間キャッシュする
+type JWKSCache struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	keys    map[string]*rsa.PublicKey
+	expires time.Time
+	fetched time.Time
+}
+
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+func (c *JWKSCache) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	fresh := c.now().Before(c.expires)
+	c.mu.RUnlock()
+	if ok && fresh {
+		return key, nil
+	}
+
+	// 期限切れ、または未知のkid（鍵のローテーション直後）の場合は取り直す
+	if err := c.refresh(ctx, kid); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok = c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// refresh は期限切れなら取り直す。期限内でも kid が無ければ、前回の取得から
+// minRefetchInterval 経っていれば取り直す（Google は期限を待たずに鍵を入れ替えることがある）
+func (c *JWKSCache) refresh(ctx context.Context, kid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.keys != nil && now.Before(c.expires) {
+		// 待っている間に他のリクエストが取り直していれば、それを使う
+		if _, ok := c.keys[kid]; ok || now.Sub(c.fetched) < minRefetchInterval {
+			return nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+	keys, err := set.publicKeys()
+	if err != nil {
+		return err
+	}
+
+	c.keys = keys
+	c.fetched = c.now()
+	c.expires = c.fetched.Add(maxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// ParseJWKS はJWKS形式のJSONから鍵セットを作る
+func ParseJWKS(data []byte) (StaticKeySet, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	return set.publicKeys()
+}
+
+type jwkSet struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (s jwkSet) publicKeys() (StaticKeySet, error) {
+	keys := make(StaticKeySet, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: invalid exponent: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks contains no RSA keys")
+	}
+	return keys, nil
+}
+
+// maxAge は Cache-Control ヘッダーの max-age を返す。無ければ1時間
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if v, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+				return time.Duration(sec) * time.Second
+			}
+		}
+	}
+	return time.Hour
+}
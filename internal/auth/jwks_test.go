@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer は kids の鍵を JWKS で返すテスト用のエンドポイント。取得された回数を数える
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PublicKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		var set jwkSet
+		for kid, k := range s.keys {
+			set.Keys = append(set.Keys, struct {
+				Kid string `json:"kid"`
+				Kty string `json:"kty"`
+				N   string `json:"n"`
+				E   string `json:"e"`
+			}{kid, "RSA", base64.RawURLEncoding.EncodeToString(k.N.Bytes()), base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())})
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) rotate(keys map[string]*rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestJWKSCacheRefetchesOnUnknownKid(t *testing.T) {
+	oldKey, newKey := generateKey(t), generateKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PublicKey{"old": &oldKey.PublicKey})
+
+	now := time.Unix(1_700_000_000, 0)
+	cache := NewJWKSCache(srv.URL)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := cache.Key(ctx, "old"); err != nil {
+		t.Fatalf("Key(old): %v", err)
+	}
+	// max-age 内の既知の kid は取り直さない
+	if _, err := cache.Key(ctx, "old"); err != nil || srv.fetchCount() != 1 {
+		t.Fatalf("Key(old) again: err=%v fetches=%d, want 1 fetch", err, srv.fetchCount())
+	}
+
+	// 期限内に鍵が入れ替わっても、新しい kid のトークンを受け付ける
+	srv.rotate(map[string]*rsa.PublicKey{"new": &newKey.PublicKey})
+	now = now.Add(minRefetchInterval)
+	got, err := cache.Key(ctx, "new")
+	if err != nil {
+		t.Fatalf("Key(new) after rotation: %v", err)
+	}
+	if got.N.Cmp(newKey.N) != 0 {
+		t.Fatal("Key(new) returned a different key")
+	}
+	if srv.fetchCount() != 2 {
+		t.Fatalf("fetches = %d, want 2", srv.fetchCount())
+	}
+
+	// 未知の kid が続いても、取り直すのは minRefetchInterval に1回まで
+	for range 5 {
+		if _, err := cache.Key(ctx, "bogus"); err == nil {
+			t.Fatal("Key(bogus) succeeded")
+		}
+	}
+	if srv.fetchCount() != 2 {
+		t.Fatalf("fetches = %d after unknown kids within the interval, want 2", srv.fetchCount())
+	}
+	now = now.Add(minRefetchInterval)
+	cache.Key(ctx, "bogus")
+	if srv.fetchCount() != 3 {
+		t.Fatalf("fetches = %d after the interval, want 3", srv.fetchCount())
+	}
+}
+
+func TestJWKSCacheRefetchesWhenExpired(t *testing.T) {
+	key := generateKey(t)
+	srv := newJWKSServer(t, map[string]*rsa.PublicKey{"k": &key.PublicKey})
+
+	now := time.Unix(1_700_000_000, 0)
+	cache := NewJWKSCache(srv.URL)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	cache.Key(ctx, "k")
+	now = now.Add(time.Hour)
+	if _, err := cache.Key(ctx, "k"); err != nil {
+		t.Fatalf("Key(k) after expiry: %v", err)
+	}
+	if srv.fetchCount() != 2 {
+		t.Fatalf("fetches = %d, want 2", srv.fetchCount())
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"public, max-age=19800, must-revalidate, no-transform", 19800 * time.Second},
+		{"max-age=60", time.Minute},
+		{"no-cache", time.Hour},
+		{"max-age=abc", time.Hour},
+		{"", time.Hour},
+	}
+	for _, tt := range tests {
+		if got := maxAge(tt.header); got != tt.want {
+			t.Errorf("maxAge(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
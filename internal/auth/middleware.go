@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"backend/internal/apierror"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tokenKey = "auth.token"
+
+// Middleware は Authorization: Bearer のIDトークンを検証し、結果をコンテキストに入れる。
+// ヘッダーが無いリクエストはそのまま通す（ログイン必須かどうかは RequireUser で判定）
+func Middleware(v *Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		raw, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || raw == "" {
+			apierror.Abort(c, apierror.InvalidAuthHeader)
+			return
+		}
+
+		if !authenticate(c, v, raw) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// QueryToken はクエリパラメータ name のIDトークンを検証する。
+// ブラウザの WebSocket はヘッダーを付けられないので、接続用のルートにだけ使う
+func QueryToken(v *Verifier, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if raw := c.Query(name); raw != "" && CurrentToken(c) == nil {
+			if !authenticate(c, v, raw) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func authenticate(c *gin.Context, v *Verifier, raw string) bool {
+	token, err := v.Verify(c.Request.Context(), raw)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidToken.Wrap(err))
+		return false
+	}
+	c.Set(tokenKey, token)
+	return true
+}
+
+// RequireUser はログインしていないリクエストを401で弾く
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if UID(c) == "" {
+			apierror.Abort(c, apierror.LoginRequired)
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentToken は検証済みトークンを返す。未ログインなら nil
+func CurrentToken(c *gin.Context) *Token {
+	if v, ok := c.Get(tokenKey); ok {
+		return v.(*Token)
+	}
+	return nil
+}
+
+// UID は検証済みのFirebase UIDを返す。未ログインなら空文字
+func UID(c *gin.Context) string {
+	if t := CurrentToken(c); t != nil {
+		return t.UID
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"backend/internal/apierror"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// エラーの応答は apierror.Middleware が書くので、テストのルーターにも付ける
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := generateKey(t)
+	valid := signRS256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, testClaims())
+	expired := testClaims()
+	expired["exp"] = testNow.Add(-time.Hour).Unix()
+
+	r := gin.New()
+	r.Use(apierror.Middleware(quietLogger), Middleware(newTestVerifier(key)))
+	r.GET("/optional", func(c *gin.Context) { c.String(http.StatusOK, UID(c)) })
+	r.GET("/required", RequireUser(), func(c *gin.Context) { c.String(http.StatusOK, UID(c)) })
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+		body   string
+	}{
+		{"anonymous optional", "/optional", "", http.StatusOK, ""},
+		{"anonymous required", "/required", "", http.StatusUnauthorized, ""},
+		{"valid token", "/required", "Bearer " + valid, http.StatusOK, "user-1"},
+		{"valid token optional", "/optional", "Bearer " + valid, http.StatusOK, "user-1"},
+		{"not bearer", "/optional", "Basic abc", http.StatusUnauthorized, ""},
+		{"empty bearer", "/optional", "Bearer ", http.StatusUnauthorized, ""},
+		{"expired token", "/optional", "Bearer " + signRS256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, expired), http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusOK && w.Body.String() != tt.body {
+				t.Fatalf("uid = %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestQueryToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := generateKey(t)
+	valid := signRS256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, testClaims())
+
+	r := gin.New()
+	r.Use(apierror.Middleware(quietLogger))
+	r.GET("/ws", QueryToken(newTestVerifier(key), "access_token"), RequireUser(), func(c *gin.Context) {
+		c.String(http.StatusOK, UID(c))
+	})
+
+	for _, tt := range []struct {
+		query  string
+		status int
+	}{
+		{"?access_token=" + valid, http.StatusOK},
+		{"?access_token=bogus", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET /ws%s status = %d, want %d", tt.query[:min(len(tt.query), 20)], w.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+// Token は検証済みのFirebase IDトークンの内容
+type Token struct {
+	UID           string
+	Email         string
+	EmailVerified bool // メールアドレスの持ち主であることを Firebase が確認済みか
+	Name          string
+	Picture       string
+	IssuedAt      time.Time
+	Expires       time.Time
+}
+
+// Verifier はFirebase IDトークン(RS256のJWT)を検証する
+type Verifier struct {
+	projectID string
+	keys      KeySource
+	now       func() time.Time
+	leeway    time.Duration
+}
+
+func NewVerifier(projectID string, keys KeySource) *Verifier {
+	return &Verifier{
+		projectID: projectID,
+		keys:      keys,
+		now:       time.Now,
+		leeway:    time.Minute,
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type claims struct {
+	Issuer   string `json:"iss"`
+	Audience string `json:"aud"`
+	Subject  string `json:"sub"`
+	IssuedAt int64  `json:"iat"`
+	Expires  int64  `json:"exp"`
+	AuthTime int64  `json:"auth_time"`
+	Email    string `json:"email"`
+	Verified bool   `json:"email_verified"`
+	Name     string `json:"name"`
+	Picture  string `json:"picture"`
+}
+
+// Verify は署名とクレーム(iss, aud, sub, exp, iat, auth_time)を検証する
+// https://firebase.google.com/docs/auth/admin/verify-id-tokens#verify_id_tokens_using_a_third-party_jwt_library
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var h jwtHeader
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	if h.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unexpected alg %q", ErrInvalidToken, h.Alg)
+	}
+
+	key, err := v.keys.Key(ctx, h.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validate(c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &Token{
+		UID:           c.Subject,
+		Email:         c.Email,
+		EmailVerified: c.Verified,
+		Name:          c.Name,
+		Picture:       c.Picture,
+		IssuedAt:      time.Unix(c.IssuedAt, 0),
+		Expires:       time.Unix(c.Expires, 0),
+	}, nil
+}
+
+func (v *Verifier) validate(c claims) error {
+	now := v.now()
+	switch {
+	case c.Audience != v.projectID:
+		return fmt.Errorf("unexpected aud %q", c.Audience)
+	case c.Issuer != "https://securetoken.google.com/"+v.projectID:
+		return fmt.Errorf("unexpected iss %q", c.Issuer)
+	case c.Subject == "" || len(c.Subject) > 128:
+		return errors.New("invalid sub")
+	case now.After(time.Unix(c.Expires, 0).Add(v.leeway)):
+		return errors.New("token expired")
+	case now.Add(v.leeway).Before(time.Unix(c.IssuedAt, 0)):
+		return errors.New("token used before issued")
+	case now.Add(v.leeway).Before(time.Unix(c.AuthTime, 0)):
+		return errors.New("auth_time is in the future")
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testProject = "test-project"
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func testClaims() map[string]any {
+	return map[string]any{
+		"iss":            "https://securetoken.google.com/" + testProject,
+		"aud":            testProject,
+		"sub":            "user-1",
+		"iat":            testNow.Add(-time.Minute).Unix(),
+		"exp":            testNow.Add(time.Hour).Unix(),
+		"auth_time":      testNow.Add(-time.Minute).Unix(),
+		"email":          "user-1@example.com",
+		"email_verified": true,
+		"name":           "User One",
+	}
+}
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signRS256 は header と claims を key で署名したトークンを作る
+func signRS256(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+	in := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(in))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestVerifier(key *rsa.PrivateKey) *Verifier {
+	v := NewVerifier(testProject, StaticKeySet{"k1": &key.PublicKey})
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	key, other := generateKey(t), generateKey(t)
+	header := map[string]any{"alg": "RS256", "kid": "k1"}
+	with := func(k string, v any) map[string]any {
+		c := testClaims()
+		c[k] = v
+		return c
+	}
+
+	// 公開鍵そのものを HMAC の鍵にする、alg の取り違えを狙ったトークン
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsIn := segment(t, map[string]any{"alg": "HS256", "kid": "k1"}) + "." + segment(t, testClaims())
+	mac := hmac.New(sha256.New, pub)
+	mac.Write([]byte(hsIn))
+	hs256 := hsIn + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", signRS256(t, key, header, testClaims()), true},
+		{"within leeway after exp", signRS256(t, key, header, with("exp", testNow.Add(-30*time.Second).Unix())), true},
+		{"expired", signRS256(t, key, header, with("exp", testNow.Add(-2*time.Minute).Unix())), false},
+		{"issued in the future", signRS256(t, key, header, with("iat", testNow.Add(time.Hour).Unix())), false},
+		{"auth_time in the future", signRS256(t, key, header, with("auth_time", testNow.Add(time.Hour).Unix())), false},
+		{"wrong aud", signRS256(t, key, header, with("aud", "other-project")), false},
+		{"wrong iss", signRS256(t, key, header, with("iss", "https://securetoken.google.com/other-project")), false},
+		{"empty sub", signRS256(t, key, header, with("sub", "")), false},
+		{"bad signature", signRS256(t, other, header, testClaims()), false},
+		{"unknown kid", signRS256(t, key, map[string]any{"alg": "RS256", "kid": "k2"}, testClaims()), false},
+		{"alg none", segment(t, map[string]any{"alg": "none", "kid": "k1"}) + "." + segment(t, testClaims()) + ".", false},
+		{"hs256 with public key", hs256, false},
+		{"malformed", "not-a-jwt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := newTestVerifier(key).Verify(context.Background(), tt.token)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Verify() err = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() err = %v", err)
+			}
+			if tok.UID != "user-1" || tok.Email != "user-1@example.com" || !tok.EmailVerified || tok.Name != "User One" {
+				t.Fatalf("Verify() = %+v", tok)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Package config はサーバーとコマンドの設定をまとめて読み込み、起動時に検証する。
+// 既定値 → YAMLファイル (CONFIG_FILE) → 環境変数 の順に上書きする
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+type Config struct {
+	Server   Server   `yaml:"server"`
+	CORS     CORS     `yaml:"cors"`
+	DB       DB       `yaml:"db"`
+	AI       AI       `yaml:"ai"`
+	Storage  Storage  `yaml:"storage"`
+	Firebase Firebase `yaml:"firebase"`
+	Notify   Notify   `yaml:"notify"`
+	Offer    Offer    `yaml:"offer"`
+	Order    Order    `yaml:"order"`
+	Tracing  Tracing  `yaml:"tracing"`
+	Log      Log      `yaml:"log"`
+}
+
+type Server struct {
+	Port string `yaml:"port"`
+	// AdminToken は /admin 以下の運用向けルートに必要な Bearer トークン。空なら /admin は公開しない
+	AdminToken string `yaml:"admin_token"`
+	// MetricsAddr（例: ":9090"）を指定すると、トークンなしの /metrics をこのアドレスでも公開する。
+	// 外から届かない管理用ポートにだけ使う
+	MetricsAddr string `yaml:"metrics_addr"`
+	// ShutdownTimeout は SIGTERM を受けてから処理中のリクエストを待つ時間。
+	// Cloud Run は SIGTERM の10秒後に強制終了するので、それより短くする
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type CORS struct {
+	AllowOrigins []string `yaml:"allow_origins"`
+}
+
+type DB struct {
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	// InstanceConnectionName を指定すると Cloud SQL の Unix ソケット (/cloudsql/...) で繋ぐ
+	InstanceConnectionName string `yaml:"instance_connection_name"`
+	AutoMigrate            bool   `yaml:"auto_migrate"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// 起動直後は Cloud SQL Proxy がまだ立ち上がっていないことがあるので何度か繋ぎ直す
+	ConnectRetries       int           `yaml:"connect_retries"`
+	ConnectRetryInterval time.Duration `yaml:"connect_retry_interval"`
+}
+
+type AI struct {
+	Provider string `yaml:"provider"` // vertex | openai | fake
+	Model    string `yaml:"model"`    // 空なら vertex は services.DefaultGeminiModel
+	// Vertex AI のプロジェクトとリージョン（リージョンが空なら SDK が推測する）
+	ProjectID string `yaml:"project_id"`
+	Location  string `yaml:"location"`
+	// OpenAI 互換エンドポイント（Ollama や vLLM など）
+	OpenAIBaseURL string `yaml:"openai_base_url"`
+	OpenAIAPIKey  string `yaml:"openai_api_key"`
+	// Timeout は1回の生成にかけてよい時間
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type Storage struct {
+	Backend     string `yaml:"backend"` // local | gcs
+	PublicURL   string `yaml:"public_url"`
+	LocalDir    string `yaml:"local_dir"`
+	GCSBucket   string `yaml:"gcs_bucket"`
+	GCSEndpoint string `yaml:"gcs_endpoint"`
+}
+
+type Firebase struct {
+	// ProjectID が空なら ai.project_id (GCP_PROJECT_ID) を使う
+	ProjectID string `yaml:"project_id"`
+	// JWKSFile を指定するとローカルの鍵セットで検証する（オフライン検証用）
+	JWKSFile string `yaml:"jwks_file"`
+	JWKSURL  string `yaml:"jwks_url"`
+}
+
+type Notify struct {
+	SMTP    SMTP    `yaml:"smtp"`
+	WebPush WebPush `yaml:"webpush"`
+}
+
+// SMTP は Addr が空ならメールを送らない
+type SMTP struct {
+	Addr     string `yaml:"addr"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// WebPush は VAPIDPrivateKey が空なら Web Push を送らない
+type WebPush struct {
+	VAPIDPrivateKey string `yaml:"vapid_private_key"`
+	Subject         string `yaml:"subject"`
+}
+
+type Offer struct {
+	// TTL は価格提示の返答期限と、承諾後の購入期限
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type Order struct {
+	// PaymentTTL は購入から支払いまでの期限。過ぎた注文はキャンセルして商品を出品中に戻す
+	PaymentTTL time.Duration `yaml:"payment_ttl"`
+}
+
+type Tracing struct {
+	// Exporter は none（送らない）| stdout（ローカル確認用）| otlp（OTLP/HTTP でコレクターへ送る）
+	Exporter string `yaml:"exporter"`
+	// Endpoint は OTLP の送り先（例: localhost:4318）。空なら OTEL_EXPORTER_OTLP_ENDPOINT か SDK の既定
+	Endpoint string `yaml:"endpoint"`
+	// Insecure なら TLS を使わずに送る（同じホストのコレクター向け）
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"` // フロントから届いたトレースは、その判断に従う
+}
+
+type Log struct {
+	Level string `yaml:"level"` // debug | info | warn | error
+	// Format は json（Cloud Logging の構造化ログ）| text（ローカルで読む用）
+	Format string `yaml:"format"`
+}
+
+// Default は何も指定しないときの設定
+func Default() *Config {
+	return &Config{
+		Server: Server{Port: "8080", ShutdownTimeout: 8 * time.Second},
+		CORS: CORS{AllowOrigins: []string{
+			"http://localhost:5173",
+			"https://hackathon-frontend-jet.vercel.app",
+		}},
+		DB: DB{
+			Port:                 "3306",
+			MaxOpenConns:         25,
+			MaxIdleConns:         10,
+			ConnMaxLifetime:      30 * time.Minute,
+			ConnMaxIdleTime:      5 * time.Minute,
+			ConnectRetries:       10,
+			ConnectRetryInterval: 3 * time.Second,
+		},
+		AI:      AI{Provider: "vertex", Timeout: 2 * time.Minute},
+		Storage: Storage{Backend: "local", PublicURL: "/api/images", LocalDir: "uploads"},
+		Offer:   Offer{TTL: 24 * time.Hour},
+		Order:   Order{PaymentTTL: 72 * time.Hour},
+		Tracing: Tracing{Exporter: "none", ServiceName: "fleamarket-api", SampleRatio: 1},
+		Log:     Log{Level: "info", Format: "json"},
+	}
+}
+
+// Load は CONFIG_FILE（省略可）と環境変数から設定を読む。検証は呼び出し側で Validate する
+// （マイグレーション用のコマンドは DB の設定だけ確かめればよいため）
+func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile は path の YAML（空なら読まない）を既定値に重ね、さらに環境変数で上書きする
+func LoadFile(path string) (*Config, error) {
+	c := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("設定ファイルを読み込めません: %w", err)
+		}
+		// キーの打ち間違いに気づけるよう、知らない項目はエラーにする
+		if err := yaml.UnmarshalWithOptions(data, c, yaml.DisallowUnknownField()); err != nil {
+			return nil, fmt.Errorf("設定ファイル %s を解析できません:\n%w", path, err)
+		}
+	}
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if c.Firebase.ProjectID == "" {
+		c.Firebase.ProjectID = c.AI.ProjectID
+	}
+	return c, nil
+}
+
+// Redacted は設定の一覧（キーは YAML のパス）。パスワードや鍵は伏せ字にする
+func (c *Config) Redacted() map[string]any {
+	dump := make(map[string]any)
+	for _, s := range c.settings() {
+		switch p := s.ptr.(type) {
+		case *string:
+			if s.secret && *p != "" {
+				dump[s.key] = "********"
+			} else {
+				dump[s.key] = *p
+			}
+		case *time.Duration:
+			dump[s.key] = p.String()
+		case *int:
+			dump[s.key] = *p
+		case *float64:
+			dump[s.key] = *p
+		case *bool:
+			dump[s.key] = *p
+		case *[]string:
+			dump[s.key] = *p
+		}
+	}
+	return dump
+}
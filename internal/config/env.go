@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting は設定項目1つ分。env が空でなければその環境変数で上書きできる
+type setting struct {
+	env    string
+	key    string // YAML でのパス
+	ptr    any    // *string, *int, *float64, *bool, *time.Duration, *[]string
+	secret bool   // Redacted で伏せる
+}
+
+func (c *Config) settings() []setting {
+	var all []setting
+	all = append(all, c.Server.settings()...)
+	all = append(all, c.CORS.settings()...)
+	all = append(all, c.DB.settings()...)
+	all = append(all, c.AI.settings()...)
+	all = append(all, c.Storage.settings()...)
+	all = append(all, c.Firebase.settings()...)
+	all = append(all, c.Notify.settings()...)
+	all = append(all, c.Offer.settings()...)
+	all = append(all, c.Order.settings()...)
+	all = append(all, c.Tracing.settings()...)
+	all = append(all, c.Log.settings()...)
+	return all
+}
+
+func (s *Server) settings() []setting {
+	return []setting{
+		{env: "PORT", key: "server.port", ptr: &s.Port},
+		{env: "ADMIN_TOKEN", key: "server.admin_token", ptr: &s.AdminToken, secret: true},
+		{env: "METRICS_ADDR", key: "server.metrics_addr", ptr: &s.MetricsAddr},
+		{env: "SHUTDOWN_TIMEOUT", key: "server.shutdown_timeout", ptr: &s.ShutdownTimeout},
+	}
+}
+
+func (c *CORS) settings() []setting {
+	return []setting{
+		{env: "CORS_ALLOW_ORIGINS", key: "cors.allow_origins", ptr: &c.AllowOrigins},
+	}
+}
+
+func (d *DB) settings() []setting {
+	return []setting{
+		{env: "MYSQL_USER", key: "db.user", ptr: &d.User},
+		{env: "MYSQL_PASSWORD", key: "db.password", ptr: &d.Password, secret: true},
+		{env: "MYSQL_DATABASE", key: "db.name", ptr: &d.Name},
+		{env: "MYSQL_HOST", key: "db.host", ptr: &d.Host},
+		{env: "DB_PORT", key: "db.port", ptr: &d.Port},
+		{env: "INSTANCE_CONNECTION_NAME", key: "db.instance_connection_name", ptr: &d.InstanceConnectionName},
+		{env: "DB_AUTO_MIGRATE", key: "db.auto_migrate", ptr: &d.AutoMigrate},
+		{env: "DB_MAX_OPEN_CONNS", key: "db.max_open_conns", ptr: &d.MaxOpenConns},
+		{env: "DB_MAX_IDLE_CONNS", key: "db.max_idle_conns", ptr: &d.MaxIdleConns},
+		{env: "DB_CONN_MAX_LIFETIME", key: "db.conn_max_lifetime", ptr: &d.ConnMaxLifetime},
+		{env: "DB_CONN_MAX_IDLE_TIME", key: "db.conn_max_idle_time", ptr: &d.ConnMaxIdleTime},
+		{env: "DB_CONNECT_RETRIES", key: "db.connect_retries", ptr: &d.ConnectRetries},
+		{env: "DB_CONNECT_RETRY_INTERVAL", key: "db.connect_retry_interval", ptr: &d.ConnectRetryInterval},
+	}
+}
+
+func (a *AI) settings() []setting {
+	return []setting{
+		{env: "LLM_PROVIDER", key: "ai.provider", ptr: &a.Provider},
+		{env: "LLM_MODEL", key: "ai.model", ptr: &a.Model},
+		{env: "GCP_PROJECT_ID", key: "ai.project_id", ptr: &a.ProjectID},
+		{env: "GCP_LOCATION", key: "ai.location", ptr: &a.Location},
+		{env: "OPENAI_BASE_URL", key: "ai.openai_base_url", ptr: &a.OpenAIBaseURL},
+		{env: "OPENAI_API_KEY", key: "ai.openai_api_key", ptr: &a.OpenAIAPIKey, secret: true},
+		{env: "LLM_TIMEOUT", key: "ai.timeout", ptr: &a.Timeout},
+	}
+}
+
+func (s *Storage) settings() []setting {
+	return []setting{
+		{env: "STORAGE_BACKEND", key: "storage.backend", ptr: &s.Backend},
+		{env: "STORAGE_PUBLIC_URL", key: "storage.public_url", ptr: &s.PublicURL},
+		{env: "STORAGE_LOCAL_DIR", key: "storage.local_dir", ptr: &s.LocalDir},
+		{env: "STORAGE_GCS_BUCKET", key: "storage.gcs_bucket", ptr: &s.GCSBucket},
+		{env: "STORAGE_GCS_ENDPOINT", key: "storage.gcs_endpoint", ptr: &s.GCSEndpoint},
+	}
+}
+
+func (f *Firebase) settings() []setting {
+	return []setting{
+		{env: "FIREBASE_PROJECT_ID", key: "firebase.project_id", ptr: &f.ProjectID},
+		{env: "FIREBASE_JWKS_FILE", key: "firebase.jwks_file", ptr: &f.JWKSFile},
+		{env: "FIREBASE_JWKS_URL", key: "firebase.jwks_url", ptr: &f.JWKSURL},
+	}
+}
+
+func (n *Notify) settings() []setting {
+	return []setting{
+		{env: "SMTP_ADDR", key: "notify.smtp.addr", ptr: &n.SMTP.Addr},
+		{env: "SMTP_FROM", key: "notify.smtp.from", ptr: &n.SMTP.From},
+		{env: "SMTP_USERNAME", key: "notify.smtp.username", ptr: &n.SMTP.Username},
+		{env: "SMTP_PASSWORD", key: "notify.smtp.password", ptr: &n.SMTP.Password, secret: true},
+		{env: "VAPID_PRIVATE_KEY", key: "notify.webpush.vapid_private_key", ptr: &n.WebPush.VAPIDPrivateKey, secret: true},
+		{env: "VAPID_SUBJECT", key: "notify.webpush.subject", ptr: &n.WebPush.Subject},
+	}
+}
+
+func (o *Offer) settings() []setting {
+	return []setting{
+		{env: "OFFER_TTL", key: "offer.ttl", ptr: &o.TTL},
+	}
+}
+
+func (o *Order) settings() []setting {
+	return []setting{
+		{env: "ORDER_PAYMENT_TTL", key: "order.payment_ttl", ptr: &o.PaymentTTL},
+	}
+}
+
+func (t *Tracing) settings() []setting {
+	return []setting{
+		{env: "TRACING_EXPORTER", key: "tracing.exporter", ptr: &t.Exporter},
+		{env: "TRACING_ENDPOINT", key: "tracing.endpoint", ptr: &t.Endpoint},
+		{env: "TRACING_INSECURE", key: "tracing.insecure", ptr: &t.Insecure},
+		{env: "TRACING_SERVICE_NAME", key: "tracing.service_name", ptr: &t.ServiceName},
+		{env: "TRACING_SAMPLE_RATIO", key: "tracing.sample_ratio", ptr: &t.SampleRatio},
+	}
+}
+
+func (l *Log) settings() []setting {
+	return []setting{
+		{env: "LOG_LEVEL", key: "log.level", ptr: &l.Level},
+		{env: "LOG_FORMAT", key: "log.format", ptr: &l.Format},
+	}
+}
+
+// applyEnv は空でない環境変数で設定を上書きする。読めない値はすべてまとめて返す
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, s := range c.settings() {
+		v, ok := lookup(s.env)
+		if !ok || v == "" {
+			continue
+		}
+		if err := s.set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s setting) set(v string) error {
+	switch p := s.ptr.(type) {
+	case *string:
+		*p = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("整数で指定してください (%q)", v)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("数値で指定してください (%q)", v)
+		}
+		*p = f
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("true か false で指定してください (%q)", v)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("30s や 5m のような時間で指定してください (%q)", v)
+		}
+		*p = d
+	case *[]string:
+		// カンマ区切り
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// checker は検証エラーを項目名（環境変数と YAML のキー）付きで集める
+type checker struct {
+	settings []setting
+	errs     []error
+}
+
+func (ch *checker) fail(ptr any, format string, args ...any) {
+	name := "?"
+	for _, s := range ch.settings {
+		if s.ptr == ptr {
+			name = fmt.Sprintf("%s (%s)", s.env, s.key)
+			break
+		}
+	}
+	ch.errs = append(ch.errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+func (ch *checker) required(ptr *string) {
+	if *ptr == "" {
+		ch.fail(ptr, "指定してください")
+	}
+}
+
+func (ch *checker) oneOf(ptr *string, values ...string) {
+	for _, v := range values {
+		if *ptr == v {
+			return
+		}
+	}
+	ch.fail(ptr, "%s のいずれかを指定してください (%q)", strings.Join(values, ", "), *ptr)
+}
+
+func (ch *checker) url(ptr *string, schemes ...string) {
+	u, err := url.Parse(*ptr)
+	if err != nil || u.Host == "" || !contains(schemes, u.Scheme) {
+		ch.fail(ptr, "%s:// で始まるURLを指定してください (%q)", strings.Join(schemes, ":// か "), *ptr)
+	}
+}
+
+func (ch *checker) err() error {
+	return errors.Join(ch.errs...)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate は API サーバーの起動に必要な設定をすべて検証し、問題をまとめて返す
+func (c *Config) Validate() error {
+	return errors.Join(
+		c.Server.Validate(),
+		c.CORS.Validate(),
+		c.DB.Validate(),
+		c.AI.Validate(),
+		c.Storage.Validate(),
+		c.Firebase.Validate(),
+		c.Notify.Validate(),
+		c.Offer.Validate(),
+		c.Order.Validate(),
+		c.Tracing.Validate(),
+		c.Log.Validate(),
+	)
+}
+
+func (s *Server) Validate() error {
+	ch := checker{settings: s.settings()}
+	if n, err := strconv.Atoi(s.Port); err != nil || n < 1 || n > 65535 {
+		ch.fail(&s.Port, "1〜65535 のポート番号を指定してください (%q)", s.Port)
+	}
+	// 推測されにくい長さを求める（空なら /admin を公開しないだけ）
+	if s.AdminToken != "" && len(s.AdminToken) < 16 {
+		ch.fail(&s.AdminToken, "16文字以上にしてください")
+	}
+	if s.MetricsAddr != "" {
+		if _, port, err := net.SplitHostPort(s.MetricsAddr); err != nil || port == s.Port {
+			ch.fail(&s.MetricsAddr, "API と別のポートを :9090 のように指定してください (%q)", s.MetricsAddr)
+		}
+	}
+	if s.ShutdownTimeout <= 0 {
+		ch.fail(&s.ShutdownTimeout, "正の時間を指定してください (%s)", s.ShutdownTimeout)
+	}
+	return ch.err()
+}
+
+func (c *CORS) Validate() error {
+	ch := checker{settings: c.settings()}
+	if len(c.AllowOrigins) == 0 {
+		ch.fail(&c.AllowOrigins, "許可するオリジンを1つ以上指定してください")
+	}
+	for _, origin := range c.AllowOrigins {
+		// オリジンはスキームとホスト（とポート）だけで、パスや末尾のスラッシュは付けない
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			ch.fail(&c.AllowOrigins, "https://example.com の形で指定してください (%q)", origin)
+		}
+	}
+	return ch.err()
+}
+
+func (d *DB) Validate() error {
+	ch := checker{settings: d.settings()}
+	ch.required(&d.User)
+	ch.required(&d.Name)
+	if d.InstanceConnectionName == "" {
+		ch.required(&d.Host)
+		if n, err := strconv.Atoi(d.Port); err != nil || n < 1 || n > 65535 {
+			ch.fail(&d.Port, "1〜65535 のポート番号を指定してください (%q)", d.Port)
+		}
+	}
+	if d.MaxOpenConns < 0 {
+		ch.fail(&d.MaxOpenConns, "0（無制限）以上にしてください")
+	}
+	if d.MaxIdleConns < 0 {
+		ch.fail(&d.MaxIdleConns, "0 以上にしてください")
+	}
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		ch.fail(&d.MaxIdleConns, "max_open_conns (%d) 以下にしてください", d.MaxOpenConns)
+	}
+	if d.ConnMaxLifetime < 0 {
+		ch.fail(&d.ConnMaxLifetime, "0（無制限）以上にしてください")
+	}
+	if d.ConnMaxIdleTime < 0 {
+		ch.fail(&d.ConnMaxIdleTime, "0（無制限）以上にしてください")
+	}
+	if d.ConnectRetries < 1 {
+		ch.fail(&d.ConnectRetries, "1 以上にしてください")
+	}
+	if d.ConnectRetryInterval < 0 {
+		ch.fail(&d.ConnectRetryInterval, "0 以上にしてください")
+	}
+	return ch.err()
+}
+
+func (a *AI) Validate() error {
+	ch := checker{settings: a.settings()}
+	ch.oneOf(&a.Provider, "vertex", "openai", "fake")
+	switch a.Provider {
+	case "vertex":
+		// 以前は最初のAIリクエストまで気づけなかったので、起動時に確かめる
+		ch.required(&a.ProjectID)
+	case "openai":
+		ch.url(&a.OpenAIBaseURL, "http", "https")
+		ch.required(&a.Model)
+	}
+	if a.Timeout <= 0 {
+		ch.fail(&a.Timeout, "正の時間を指定してください (%s)", a.Timeout)
+	}
+	return ch.err()
+}
+
+func (s *Storage) Validate() error {
+	ch := checker{settings: s.settings()}
+	ch.required(&s.PublicURL)
+	ch.oneOf(&s.Backend, "local", "gcs")
+	switch s.Backend {
+	case "local":
+		ch.required(&s.LocalDir)
+	case "gcs":
+		ch.required(&s.GCSBucket)
+		if s.GCSEndpoint != "" {
+			ch.url(&s.GCSEndpoint, "http", "https")
+		}
+	}
+	return ch.err()
+}
+
+func (f *Firebase) Validate() error {
+	ch := checker{settings: f.settings()}
+	if f.ProjectID == "" {
+		ch.fail(&f.ProjectID, "指定してください（GCP_PROJECT_ID でも可）")
+	}
+	if f.JWKSURL != "" {
+		ch.url(&f.JWKSURL, "https", "http")
+	}
+	return ch.err()
+}
+
+func (n *Notify) Validate() error {
+	ch := checker{settings: n.settings()}
+	if n.SMTP.Addr != "" {
+		ch.required(&n.SMTP.From)
+		if n.SMTP.Password != "" && n.SMTP.Username == "" {
+			ch.fail(&n.SMTP.Username, "パスワードを使うときはユーザー名も指定してください")
+		}
+	}
+	if n.WebPush.VAPIDPrivateKey != "" {
+		if !strings.HasPrefix(n.WebPush.Subject, "mailto:") && !strings.HasPrefix(n.WebPush.Subject, "https:") {
+			ch.fail(&n.WebPush.Subject, "mailto: か https: で始まる連絡先を指定してください (%q)", n.WebPush.Subject)
+		}
+	}
+	return ch.err()
+}
+
+func (o *Offer) Validate() error {
+	ch := checker{settings: o.settings()}
+	if o.TTL <= 0 {
+		ch.fail(&o.TTL, "正の時間を指定してください (%s)", o.TTL)
+	}
+	return ch.err()
+}
+
+func (o *Order) Validate() error {
+	ch := checker{settings: o.settings()}
+	if o.PaymentTTL <= 0 {
+		ch.fail(&o.PaymentTTL, "正の時間を指定してください (%s)", o.PaymentTTL)
+	}
+	return ch.err()
+}
+
+func (t *Tracing) Validate() error {
+	ch := checker{settings: t.settings()}
+	ch.oneOf(&t.Exporter, "none", "stdout", "otlp")
+	if t.Exporter != "none" {
+		ch.required(&t.ServiceName)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		ch.fail(&t.SampleRatio, "0 から 1 の間で指定してください (%g)", t.SampleRatio)
+	}
+	return ch.err()
+}
+
+func (l *Log) Validate() error {
+	ch := checker{settings: l.settings()}
+	ch.oneOf(&l.Level, "debug", "info", "warn", "error")
+	ch.oneOf(&l.Format, "json", "text")
+	return ch.err()
+}
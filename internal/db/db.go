@@ -1,53 +1,77 @@
 package db
 
 import (
+	"backend/internal/config"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var DB *sql.DB
 
-func InitDB() {
-	user := os.Getenv("MYSQL_USER")
-	pass := os.Getenv("MYSQL_PASSWORD")
-	name := os.Getenv("MYSQL_DATABASE")
-	host := os.Getenv("MYSQL_HOST")
-	port := os.Getenv("DB_PORT")
-	instanceConnectionName := os.Getenv("INSTANCE_CONNECTION_NAME")
+// InitDB は cfg の接続先に繋いで DB に入れる。繋がらなければ終了する
+func InitDB(cfg config.DB) {
+	var err error
+	if DB, err = Open(cfg); err != nil {
+		slog.Error("database connection failed", "error", err)
+		os.Exit(1)
+	}
+}
 
+// Open は接続プールを作り、繋がるまで cfg.ConnectRetries 回まで試す
+func Open(cfg config.DB) (*sql.DB, error) {
 	var dsn string
 
 	// Cloud Run等で Cloud SQL Auth Proxy 経由（Unixソケット）で繋ぐ場合
-	if instanceConnectionName != "" {
+	if cfg.InstanceConnectionName != "" {
 		// DSN形式: user:pass@unix(/cloudsql/INSTANCE_CONNECTION_NAME)/dbname
-		dsn = fmt.Sprintf("%s:%s@unix(/cloudsql/%s)/%s?parseTime=true", 
-			user, pass, instanceConnectionName, name)
-		log.Printf("Connecting to Cloud SQL via Unix Socket...")
+		dsn = fmt.Sprintf("%s:%s@unix(/cloudsql/%s)/%s?parseTime=true",
+			cfg.User, cfg.Password, cfg.InstanceConnectionName, cfg.Name)
+		slog.Info("connecting to database", "via", "unix", "instance", cfg.InstanceConnectionName)
 	} else {
 		// ローカル開発用（TCP接続）
-		if port == "" { port = "3306" }
-		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", 
-			user, pass, host, port, name)
-		log.Printf("Connecting to DB via TCP (%s)...", host)
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+			cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+		slog.Info("connecting to database", "via", "tcp", "host", cfg.Host)
 	}
 
-	var err error
-	for i := 0; i < 10; i++ {
-		DB, err = sql.Open("mysql", dsn)
-		if err == nil {
-			err = DB.Ping()
-			if err == nil {
-				log.Println("Database Connected!")
-				return
-			}
+	// クエリごとにスパンを作る（リクエストなど親のスパンがあるときだけ。起動時の Ping などは除く）
+	db, err := otelsql.Open("mysql", dsn,
+		otelsql.WithAttributes(semconv.DBSystemNameMySQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			DisableErrSkip:       true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	for i := 0; i < cfg.ConnectRetries; i++ {
+		if err = db.Ping(); err == nil {
+			slog.Info("database connected")
+			return db, nil
 		}
-		log.Printf("Retry %d/10: %v", i+1, err)
-		time.Sleep(3 * time.Second)
+		slog.Warn("database ping failed", "attempt", i+1, "max_attempts", cfg.ConnectRetries, "error", err)
+		time.Sleep(cfg.ConnectRetryInterval)
 	}
-	log.Fatal("DB Connection Failed after 10 attempts")
-}
\ No newline at end of file
+	db.Close()
+	return nil, fmt.Errorf("DB Connection Failed after %d attempts: %w", cfg.ConnectRetries, err)
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"comments only", "-- 説明\n# 説明\n/* 説明 */\n", nil},
+		{"two statements", "CREATE TABLE a (id INT);\n\nDROP TABLE b;\n", []string{"CREATE TABLE a (id INT)", "DROP TABLE b"}},
+		{"without trailing semicolon", "SELECT 1", []string{"SELECT 1"}},
+		{"same line", "SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"multi line", "UPDATE products p\nSET like_count = 0;\n", []string{"UPDATE products p\nSET like_count = 0"}},
+		{"leading comment is kept", "-- 数え直す\nUPDATE a SET n = 0;", []string{"-- 数え直す\nUPDATE a SET n = 0"}},
+		{"trailing comment is dropped", "SELECT 1; -- 終わり\n", []string{"SELECT 1"}},
+		{"semicolon in string", "INSERT INTO a VALUES ('x;');\n", []string{"INSERT INTO a VALUES ('x;')"}},
+		{"string ends with semicolon at line end", "INSERT INTO a VALUES ('x;\ny');", []string{"INSERT INTO a VALUES ('x;\ny')"}},
+		{"doubled quote", "SELECT 'it''s;';", []string{"SELECT 'it''s;'"}},
+		{"backslash escape", `SELECT 'a\';b';`, []string{`SELECT 'a\';b'`}},
+		{"double quoted", `SELECT "a;b";`, []string{`SELECT "a;b"`}},
+		{"backquoted identifier", "SELECT `a;b` FROM t;", []string{"SELECT `a;b` FROM t"}},
+		{"semicolon in line comment", "SELECT 1 -- a;\n, 2;", []string{"SELECT 1 -- a;\n, 2"}},
+		{"semicolon in hash comment", "SELECT 1 # a;\n, 2;", []string{"SELECT 1 # a;\n, 2"}},
+		{"semicolon in block comment", "SELECT 1 /* a; b */ , 2;", []string{"SELECT 1 /* a; b */ , 2"}},
+		{"double dash without space is not a comment", "SELECT 1--1;", []string{"SELECT 1--1"}},
+		{"executable comment", "/*!40101 SET NAMES utf8mb4 */;", []string{"/*!40101 SET NAMES utf8mb4 */"}},
+		{"japanese", "-- 商品；説明\nSELECT '商品;';", []string{"-- 商品；説明\nSELECT '商品;'"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+// 埋め込んだマイグレーションはすべて up と down が揃い、1 から欠番なく並ぶ
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations")
+	}
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			t.Fatalf("migration %d_%s: want version %d", mig.Version, mig.Name, i+1)
+		}
+		for _, script := range []string{mig.Up, mig.Down} {
+			for _, stmt := range splitStatements(script) {
+				if strings.TrimSpace(stmt) == "" {
+					t.Errorf("migration %d_%s has an empty statement", mig.Version, mig.Name)
+				}
+			}
+		}
+	}
+	if len(splitStatements(migrations[0].Up)) == 0 {
+		t.Error("0001 up has no statements")
+	}
+}
+
+// 途中で失敗しても流し直せるように、作り直す表は先に消してから作る
+func TestLikesPrimaryKeyRerunnable(t *testing.T) {
+	migrations, err := loadMigrations(migrationFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var up string
+	for _, mig := range migrations {
+		if mig.Name == "likes_primary_key" {
+			up = mig.Up
+		}
+	}
+	stmts := splitStatements(up)
+	index := func(re string) int {
+		for i, stmt := range stmts {
+			if regexp.MustCompile(re).MatchString(stmt) {
+				return i
+			}
+		}
+		t.Fatalf("no statement matches %s", re)
+		return -1
+	}
+	if !(index(`DROP TABLE IF EXISTS likes_new`) < index(`CREATE TABLE likes_new`) &&
+		index(`DROP TABLE IF EXISTS likes_old`) < index(`RENAME TABLE likes TO likes_old`)) {
+		t.Fatalf("leftovers are not dropped first: %q", stmts)
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"missing down", fstest.MapFS{"migrations/0001_a.up.sql": up}},
+		{"missing up", fstest.MapFS{"migrations/0001_a.down.sql": up}},
+		{"conflicting names", fstest.MapFS{"migrations/0001_a.up.sql": up, "migrations/0001_b.down.sql": up}},
+		{"unexpected file", fstest.MapFS{"migrations/0001_a.sql": up}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadMigrations(tt.files); err == nil {
+				t.Fatal("err = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS likes;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS users;
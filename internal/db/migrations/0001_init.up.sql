@@ -0,0 +1,41 @@
+-- 既存の本番DBにもそのまま適用できるよう IF NOT EXISTS にしている
+CREATE TABLE IF NOT EXISTS users (
+    id          VARCHAR(128)  NOT NULL PRIMARY KEY, -- Firebase UID
+    name        VARCHAR(255)  NOT NULL DEFAULT '',
+    email       VARCHAR(255)  NOT NULL DEFAULT '',
+    avatar_url  VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at  DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS products (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    seller_id   VARCHAR(128) NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL,
+    price       INT          NOT NULL,
+    image_url   MEDIUMTEXT   NOT NULL, -- Base64のdata URLがそのまま入る
+    is_sold     BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_products_seller (seller_id),
+    INDEX idx_products_created (created_at)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS likes (
+    user_id     VARCHAR(128) NOT NULL,
+    product_id  INT          NOT NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id),
+    INDEX idx_likes_product (product_id)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS messages (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    product_id  INT          NOT NULL,
+    sender_id   VARCHAR(128) NOT NULL,
+    receiver_id VARCHAR(128) NOT NULL,
+    content     TEXT         NOT NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_messages_product (product_id, created_at),
+    INDEX idx_messages_sender (sender_id),
+    INDEX idx_messages_receiver (receiver_id)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,14 @@
+-- 注文テーブル（購入ごとに1行。products.is_sold は進行中の注文があるかどうかを表す）
+CREATE TABLE IF NOT EXISTS orders (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    product_id  INT          NOT NULL,
+    buyer_id    VARCHAR(128) NOT NULL,
+    seller_id   VARCHAR(128) NOT NULL,
+    price       INT          NOT NULL,
+    status      VARCHAR(32)  NOT NULL DEFAULT 'pending_payment',
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_orders_product (product_id),
+    INDEX idx_orders_buyer (buyer_id, created_at),
+    INDEX idx_orders_seller (seller_id, created_at)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,3 @@
+-- 画像本体はストレージに置き、products には参照（キー）だけを持つ。
+-- image_url には移行前の Base64 が残っていることがある（cmd/migrate-images で移す）
+ALTER TABLE products ADD COLUMN image_key VARCHAR(255) NOT NULL DEFAULT '' AFTER image_url;
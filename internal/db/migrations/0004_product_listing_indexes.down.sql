@@ -0,0 +1,6 @@
+ALTER TABLE products
+    ADD INDEX idx_products_seller (seller_id),
+    DROP INDEX idx_products_seller_id,
+    DROP INDEX idx_products_sold_price,
+    DROP INDEX idx_products_sold_id,
+    DROP INDEX idx_products_price;
@@ -0,0 +1,7 @@
+-- 商品一覧のキーセットページング用。どの並び順も最後のキーは id
+ALTER TABLE products
+    ADD INDEX idx_products_price (price, id),
+    ADD INDEX idx_products_sold_id (is_sold, id),
+    ADD INDEX idx_products_sold_price (is_sold, price, id),
+    ADD INDEX idx_products_seller_id (seller_id, id),
+    DROP INDEX idx_products_seller;
@@ -0,0 +1,2 @@
+-- 商品検索用。日本語は空白で区切られないので ngram パーサーで分割する（ngram_token_size は既定の2）
+ALTER TABLE products ADD FULLTEXT INDEX ft_products_title_description (title, description) WITH PARSER ngram;
@@ -0,0 +1,4 @@
+ALTER TABLE messages
+    DROP INDEX idx_messages_thread;
+
+DROP TABLE IF EXISTS conversation_reads;
@@ -0,0 +1,13 @@
+-- 会話（商品 × 相手）ごとに、どのメッセージまで読んだかを参加者ごとに持つ
+CREATE TABLE IF NOT EXISTS conversation_reads (
+    user_id              VARCHAR(128) NOT NULL,
+    product_id           INT          NOT NULL,
+    partner_id           VARCHAR(128) NOT NULL,
+    last_read_message_id INT          NOT NULL DEFAULT 0,
+    updated_at           DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id, partner_id)
+) DEFAULT CHARSET = utf8mb4;
+
+-- 未読数の集計用（相手から自分宛てで、既読位置より後のメッセージを数える）
+ALTER TABLE messages
+    ADD INDEX idx_messages_thread (product_id, sender_id, receiver_id, id);
@@ -0,0 +1,4 @@
+ALTER TABLE products
+    DROP COLUMN deleted_at,
+    DROP COLUMN is_withdrawn,
+    DROP COLUMN version;
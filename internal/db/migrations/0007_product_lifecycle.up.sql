@@ -0,0 +1,6 @@
+-- 出品の編集・取り下げ・削除用
+-- version は更新のたびに1増やし、同時編集の検出に使う。deleted_at が入っている商品は論理削除済み
+ALTER TABLE products
+    ADD COLUMN version      INT      NOT NULL DEFAULT 1,
+    ADD COLUMN is_withdrawn BOOLEAN  NOT NULL DEFAULT FALSE,
+    ADD COLUMN deleted_at   DATETIME NULL;
@@ -0,0 +1,13 @@
+-- 取引完了後の評価。1つの注文につき買い手・売り手がそれぞれ1件ずつ書ける
+CREATE TABLE IF NOT EXISTS reviews (
+    id            INT AUTO_INCREMENT PRIMARY KEY,
+    order_id      INT          NOT NULL,
+    reviewer_id   VARCHAR(128) NOT NULL,
+    reviewee_id   VARCHAR(128) NOT NULL,
+    reviewer_role VARCHAR(16)  NOT NULL, -- 評価した人の立場 (buyer / seller)
+    rating        VARCHAR(16)  NOT NULL, -- good / normal / bad
+    comment       TEXT         NOT NULL,
+    created_at    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_reviews_order_reviewer (order_id, reviewer_id),
+    INDEX idx_reviews_reviewee (reviewee_id, id)
+) DEFAULT CHARSET = utf8mb4;
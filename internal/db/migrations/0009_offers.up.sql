@@ -0,0 +1,16 @@
+-- 値下げ交渉の価格提示。提示し返されたものは parent_id で前の提示を指す
+CREATE TABLE IF NOT EXISTS offers (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    product_id  INT          NOT NULL,
+    buyer_id    VARCHAR(128) NOT NULL,
+    seller_id   VARCHAR(128) NOT NULL,
+    proposed_by VARCHAR(16)  NOT NULL, -- 提示した側 (buyer / seller)
+    price       INT          NOT NULL,
+    status      VARCHAR(16)  NOT NULL, -- pending / accepted / rejected / countered / cancelled / expired / purchased
+    parent_id   INT          NULL,
+    expires_at  DATETIME     NOT NULL, -- pending なら返答期限、accepted なら購入期限
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_offers_product (product_id, status),
+    INDEX idx_offers_expiry (status, expires_at)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,35 @@
+-- アプリ内の通知一覧。関係のない ID は0
+CREATE TABLE IF NOT EXISTS notifications (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    user_id    VARCHAR(128) NOT NULL,
+    kind       VARCHAR(16)  NOT NULL, -- like / message / purchase
+    actor_id   VARCHAR(128) NOT NULL,
+    product_id INT          NOT NULL DEFAULT 0,
+    order_id   INT          NOT NULL DEFAULT 0,
+    message_id INT          NOT NULL DEFAULT 0,
+    title      VARCHAR(255) NOT NULL,
+    body       TEXT         NOT NULL,
+    read_at    DATETIME     NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_notifications_user (user_id, id),
+    INDEX idx_notifications_unread (user_id, read_at)
+) DEFAULT CHARSET = utf8mb4;
+
+-- 既定値から変えた通知設定だけを持つ
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id VARCHAR(128) NOT NULL,
+    channel VARCHAR(16)  NOT NULL, -- in_app / email / webpush
+    kind    VARCHAR(16)  NOT NULL,
+    enabled BOOLEAN      NOT NULL,
+    PRIMARY KEY (user_id, channel, kind)
+) DEFAULT CHARSET = utf8mb4;
+
+-- ブラウザのプッシュ通知の購読。endpoint はブラウザごとに一意
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    endpoint   VARCHAR(512) NOT NULL PRIMARY KEY,
+    user_id    VARCHAR(128) NOT NULL,
+    p256dh     VARCHAR(128) NOT NULL,
+    auth       VARCHAR(64)  NOT NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_push_subscriptions_user (user_id)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,3 @@
+ALTER TABLE products
+    DROP INDEX idx_products_like_count,
+    DROP COLUMN like_count;
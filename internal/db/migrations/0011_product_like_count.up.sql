@@ -0,0 +1,8 @@
+-- いいね数を商品の行に持たせる。一覧やいいね順の並び替えのたびに likes を数えないように。
+-- likes の登録・解除と同じトランザクションで増減する
+ALTER TABLE products
+    ADD COLUMN like_count INT NOT NULL DEFAULT 0,
+    ADD INDEX idx_products_like_count (like_count, id);
+
+UPDATE products p
+SET like_count = (SELECT COUNT(*) FROM likes l WHERE l.product_id = p.id);
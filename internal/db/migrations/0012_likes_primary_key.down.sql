@@ -0,0 +1 @@
+-- 重複をまとめただけなので戻すものはない（主キーは 0001 の定義どおり残す）
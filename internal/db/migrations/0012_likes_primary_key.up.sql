@@ -0,0 +1,25 @@
+-- いいねの登録 (INSERT IGNORE) は likes の PRIMARY KEY (user_id, product_id) で重複を防いでいる。
+-- 0001 は CREATE TABLE IF NOT EXISTS なので、それ以前からある DB では主キーがなく重複行が残っていることがある。
+-- 主キー付きの表を作り直し、重複をまとめてから入れ替える。
+-- 途中で失敗しても流し直せるように、前回の残りを先に消す（likes_old が残るのは入れ替えの後なので、中身は likes にある）
+DROP TABLE IF EXISTS likes_new;
+DROP TABLE IF EXISTS likes_old;
+
+CREATE TABLE likes_new (
+    user_id     VARCHAR(128) NOT NULL,
+    product_id  INT          NOT NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id),
+    INDEX idx_likes_product (product_id)
+) DEFAULT CHARSET = utf8mb4;
+
+INSERT INTO likes_new (user_id, product_id, created_at)
+SELECT user_id, product_id, MIN(created_at) FROM likes GROUP BY user_id, product_id;
+
+RENAME TABLE likes TO likes_old, likes_new TO likes;
+
+DROP TABLE likes_old;
+
+-- 重複を数えていた分のいいね数を直す
+UPDATE products p
+SET like_count = (SELECT COUNT(*) FROM likes l WHERE l.product_id = p.id);
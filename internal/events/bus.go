@@ -0,0 +1,97 @@
+// Package events はアプリ内のイベントバス。ハンドラーは起きたことを Publish するだけで、
+// 通知などの後続処理は購読側が非同期に行う
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type Type string
+
+const (
+	ProductLiked     Type = "like"
+	MessageSent      Type = "message"
+	ProductPurchased Type = "purchase"
+)
+
+// Event は起きたことの記録。ActorID が操作した人、RecipientID が知らせたい相手。
+// 種類に関係のない項目は空のまま
+type Event struct {
+	Type        Type
+	ActorID     string
+	RecipientID string
+	ProductID   int
+	OrderID     int
+	MessageID   int
+	Price       int    // 購入価格
+	Text        string // メッセージ本文
+}
+
+// LogValue はログに載せるときの Event。メッセージ本文は載せない
+func (e Event) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("type", string(e.Type)),
+		slog.String("actor_id", e.ActorID),
+		slog.String("recipient_id", e.RecipientID),
+		slog.Int("product_id", e.ProductID),
+	)
+}
+
+type Handler func(ctx context.Context, e Event)
+
+// Bus は購読者にイベントを配る。Publish は購読者の処理を待たずに戻る
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+	wg       sync.WaitGroup
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish は購読者ごとにゴルーチンで e を渡す。リクエストが終わっても処理が続くよう、
+// ctx のキャンセルは引き継がない。nil の Bus には何もしない
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for _, h := range handlers {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			h(ctx, e)
+		}()
+	}
+}
+
+// Wait は配信中のイベントの処理がすべて終わるまで待つ。先に ctx が切れたらそのエラーを返す
+func (b *Bus) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
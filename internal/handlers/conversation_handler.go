@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- 受信箱（商品 × 相手ごとの会話一覧） ---
+func (h *Handler) GetConversations(c *gin.Context) {
+	page, err := h.Conversations.List(c.Request.Context(), auth.UID(c), repository.PageQuery{})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	h.withConversationImages(page.Items)
+	c.JSON(http.StatusOK, page.Items)
+}
+
+// withConversationImages は会話の商品の image_key から表示用のURLを埋める
+func (h *Handler) withConversationImages(conversations []models.Conversation) {
+	for i := range conversations {
+		if key := conversations[i].Product.ImageKey; key != "" {
+			conversations[i].Product.ImageURL = h.Storage.URL(key)
+		}
+	}
+}
+
+// --- 会話を既読にする ---
+// body の last_read_id まで既読にする。省略時はその会話の最新メッセージまで
+func (h *Handler) MarkConversationRead(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParam.With("product_id"))
+		return
+	}
+	partnerID := c.Param("partner_id")
+
+	var req MarkReadRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.Conversations.MarkRead(c.Request.Context(), auth.UID(c), productID, partnerID, req.LastReadID); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"backend/internal/config"
+	"backend/internal/events"
+	"backend/internal/realtime"
+	"backend/internal/repository"
+	"backend/internal/services"
+	"backend/internal/storage"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deps はハンドラーが使う依存関係。main で組み立てて渡す
+type Deps struct {
+	repository.Repositories
+	AI      *services.AI
+	Storage storage.Storage
+	Hub     *realtime.Hub
+	// OfferTTL は価格提示の返答期限と承諾後の購入期限。0 なら DefaultOfferTTL
+	OfferTTL time.Duration
+	// PaymentTTL は購入から支払いまでの期限。0 なら DefaultPaymentTTL
+	PaymentTTL time.Duration
+	// Events にはいいね・メッセージ・購入を流す。nil なら流さない
+	Events *events.Bus
+	// PushPublicKey はブラウザのプッシュ通知の購読に使う VAPID 公開鍵。空なら Web Push は無効
+	PushPublicKey string
+	// Config は /admin/config で（伏せ字にして）見せる設定。nil なら見せない
+	Config *config.Config
+	// Logger はリクエストのコンテキストを付けて書く（リクエストIDとトレースが行に載る）。nil なら slog.Default
+	Logger *slog.Logger
+}
+
+// Handler は全APIのハンドラーをメソッドとして持つ
+type Handler struct {
+	Deps
+}
+
+func New(deps Deps) *Handler {
+	if deps.Logger == nil {
+		deps.Logger = slog.Default()
+	}
+	return &Handler{Deps: deps}
+}
+
+// paramID はパスパラメータを数値IDとして読む
+func paramID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	return id, err == nil
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/realtime"
+	"backend/internal/repository/memory"
+	"backend/internal/services"
+	"backend/internal/storage"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testProject = "test-project"
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// signingKey はテスト全体で使い回すトークンの署名鍵（生成に時間がかかるので1回だけ作る）
+func signingKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		var err error
+		if testKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic(err)
+		}
+	})
+	return testKey
+}
+
+// idToken は uid の Firebase IDトークンを signingKey で作る。
+// メールアドレスは確認済み。extra のクレームで上書きでき、値が nil なら除く
+func idToken(t *testing.T, uid string, extra ...map[string]any) string {
+	t.Helper()
+	seg := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	now := time.Now().Unix()
+	claims := map[string]any{
+		"iss": "https://securetoken.google.com/" + testProject, "aud": testProject, "sub": uid,
+		"iat": now, "exp": now + 3600, "auth_time": now, "email": uid + "@example.com", "email_verified": true,
+	}
+	for _, e := range extra {
+		for k, v := range e {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+	}
+	in := seg(map[string]any{"alg": "RS256", "kid": "test"}) + "." + seg(claims)
+	digest := sha256.Sum256([]byte(in))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, signingKey(t), crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// testVerifier は signingKey の公開鍵でトークンを検証する
+func testVerifier(t *testing.T) *auth.Verifier {
+	return auth.NewVerifier(testProject, auth.StaticKeySet{"test": &signingKey(t).PublicKey})
+}
+
+// testServer は memory のリポジトリで組み立てた /api のルーター
+type testServer struct {
+	t   *testing.T
+	r   *gin.Engine
+	h   *Handler
+	llm *services.FakeLLM
+}
+
+// newTestServer は空のストアでサーバーを作る。opts で Deps を差し替えられる
+func newTestServer(t *testing.T, opts ...func(*Deps)) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	st, err := storage.NewLocal(t.TempDir(), "/api/images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := services.NewFakeLLM()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	deps := Deps{
+		Repositories: memory.NewRepositories(),
+		AI:           services.NewAI(llm, logger),
+		Storage:      st,
+		Hub:          realtime.NewHub(realtime.NewLocalPubSub()),
+		Logger:       logger,
+	}
+	for _, opt := range opts {
+		opt(&deps)
+	}
+	h := New(deps)
+	r := gin.New()
+	h.RegisterRoutes(r, testVerifier(t), RouteTimeouts(time.Minute))
+	return &testServer{t: t, r: r, h: h, llm: llm}
+}
+
+// with はサブテストの t で失敗を報告する testServer を返す（サーバーは共有する）
+func (s *testServer) with(t *testing.T) *testServer {
+	cp := *s
+	cp.t = t
+	return &cp
+}
+
+// do は uid としてリクエストを送る（uid が空なら未ログイン）。body は JSON にして送る
+func (s *testServer) do(method, path, uid string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	token := ""
+	if uid != "" {
+		token = idToken(s.t, uid)
+	}
+	return s.doToken(method, path, token, body)
+}
+
+// doToken は token を付けてリクエストを送る（token が空なら未ログイン）
+func (s *testServer) doToken(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.r.ServeHTTP(w, req)
+	return w
+}
+
+// expect は do を送り、ステータスが want でなければテストを止める。レスポンスの JSON を out に読む
+func (s *testServer) expect(want int, method, path, uid string, body, out any) {
+	s.t.Helper()
+	w := s.do(method, path, uid, body)
+	if w.Code != want {
+		s.t.Fatalf("%s %s = %d, want %d: %s", method, path, w.Code, want, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: decode %s: %v", method, path, w.Body.String(), err)
+		}
+	}
+}
+
+// expectError はエラーの応答のステータスと code を確かめる
+func (s *testServer) expectError(status int, code, method, path, uid string, body any) {
+	s.t.Helper()
+	var resp struct {
+		Code string `json:"code"`
+	}
+	s.expect(status, method, path, uid, body, &resp)
+	if resp.Code != code {
+		s.t.Fatalf("%s %s: code = %q, want %q", method, path, resp.Code, code)
+	}
+}
+
+// createUser は uid のユーザーを作る
+func (s *testServer) createUser(uid string) {
+	s.t.Helper()
+	s.expect(http.StatusOK, "POST", "/api/users/sync", uid, map[string]any{"name": uid}, nil)
+}
+
+// createProduct は seller の商品を出品して ID を返す
+func (s *testServer) createProduct(seller string, price int) int {
+	s.t.Helper()
+	var p struct {
+		ID int `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/products", seller, map[string]any{"title": fmt.Sprintf("item by %s", seller), "price": price}, &p)
+	return p.ID
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// 説明文のハイライトで一致箇所の前後に残す文字数
+const snippetContext = 40
+
+// highlight は text 中の terms を <mark> で囲む。大文字小文字は区別しない。
+// 一致しない部分は HTML エスケープするので、結果はそのまま innerHTML に入れられる
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	plain := 0
+	for i := 0; i < len(text); {
+		if n := matchAt(text, i, terms); n > 0 {
+			b.WriteString(html.EscapeString(text[plain:i]))
+			b.WriteString("<mark>")
+			b.WriteString(html.EscapeString(text[i : i+n]))
+			b.WriteString("</mark>")
+			i += n
+			plain = i
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	b.WriteString(html.EscapeString(text[plain:]))
+	return b.String()
+}
+
+// snippet は最初に一致した箇所の前後だけを切り出してハイライトする。
+// 一致が無ければ先頭から切り出す
+func snippet(text string, terms []string) string {
+	start := 0
+	for i := 0; i < len(text); {
+		if matchAt(text, i, terms) > 0 {
+			start = i
+			break
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+
+	from := start
+	for n := 0; n < snippetContext && from > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	to := start
+	for n := 0; n < 2*snippetContext && to < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+
+	out := highlight(text[from:to], terms)
+	if from > 0 {
+		out = "…" + out
+	}
+	if to < len(text) {
+		out += "…"
+	}
+	return out
+}
+
+// matchAt は text[i:] がいずれかの term で始まっていればそのバイト数を返す（長い term を優先）
+func matchAt(text string, i int, terms []string) int {
+	best := 0
+	for _, term := range terms {
+		n := prefixFold(text[i:], term)
+		if n > best {
+			best = n
+		}
+	}
+	return best
+}
+
+// prefixFold は s が大文字小文字を無視して prefix で始まるとき、s 側で一致したバイト数を返す
+func prefixFold(s, prefix string) int {
+	i := 0
+	for _, pr := range prefix {
+		if i >= len(s) {
+			return 0
+		}
+		sr, size := utf8.DecodeRuneInString(s[i:])
+		if !strings.EqualFold(string(sr), string(pr)) {
+			return 0
+		}
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/internal/storage"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- 画像アップロード (multipart の image フィールド) ---
+func (h *Handler) UploadImage(c *gin.Context) {
+	// multipart のヘッダー分の余裕を持たせてボディサイズを制限する
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, storage.MaxImageSize+64<<10)
+
+	fh, err := c.FormFile("image")
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		apierror.Abort(c, apierror.ImageTooLarge)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.ImageRequired)
+		return
+	}
+	if fh.Size > storage.MaxImageSize {
+		apierror.Abort(c, apierror.ImageTooLarge)
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		apierror.Abort(c, apierror.ImageUnreadable.Wrap(err))
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		apierror.Abort(c, apierror.ImageUnreadable.Wrap(err))
+		return
+	}
+
+	key, err := h.saveImage(c.Request.Context(), auth.UID(c), data)
+	if !h.respondImageError(c, err) {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"image_key": key, "image_url": h.Storage.URL(key)})
+}
+
+// --- 画像配信 ---
+func (h *Handler) ServeImage(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	r, contentType, err := h.Storage.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		apierror.Abort(c, apierror.ImageNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	defer r.Close()
+
+	// キーは毎回ランダムに作るので中身が変わることはない
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.DataFromReader(http.StatusOK, -1, contentType, r, nil)
+}
+
+// saveImage は画像形式を確認して uid の画像としてストレージに保存し、キーを返す
+func (h *Handler) saveImage(ctx context.Context, uid string, data []byte) (string, error) {
+	contentType, ext, err := storage.DetectImage(data)
+	if err != nil {
+		return "", err
+	}
+	key := storage.NewImageKey(storage.ProductImagePrefix(uid), ext)
+	if err := h.Storage.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// saveDataURL は Base64 の data URL の画像を uid の画像として保存し、キーを返す。駄目ならエラーを返して false
+func (h *Handler) saveDataURL(c *gin.Context, uid, dataURL string) (string, bool) {
+	data, err := storage.DecodeDataURL(dataURL)
+	if errors.Is(err, storage.ErrImageTooLarge) {
+		apierror.Abort(c, apierror.ImageTooLarge)
+		return "", false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.ImageUnreadable.Wrap(err))
+		return "", false
+	}
+	key, err := h.saveImage(c.Request.Context(), uid, data)
+	return key, h.respondImageError(c, err)
+}
+
+// checkImageKey は出品や編集で指定された image_key が、uid のアップロードした既存の画像かどうかを確かめる。
+// 駄目なら400を返して false
+func (h *Handler) checkImageKey(c *gin.Context, uid, key string) bool {
+	if !storage.ValidKey(key) || !strings.HasPrefix(key, storage.ProductImagePrefix(uid)+"/") {
+		apierror.Abort(c, apierror.Invalid("image_key", apierror.RuleNotOwned))
+		return false
+	}
+	r, _, err := h.Storage.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		apierror.Abort(c, apierror.Invalid("image_key", apierror.RuleNotFound))
+		return false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return false
+	}
+	r.Close()
+	return true
+}
+
+// maxImageBody は画像を含みうる JSON ボディ（出品・編集・AI）の上限。
+// Base64 の data URL で届く画像（MaxImageSize まで）に、ほかの項目の分を足す
+var maxImageBody = int64(base64.StdEncoding.EncodedLen(storage.MaxImageSize)) + 64<<10
+
+// bindImageJSON は画像を含みうるボディを maxImageBody までに制限して読む。駄目なら400か413を返して false
+func bindImageJSON(c *gin.Context, req any) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageBody)
+	err := c.ShouldBindJSON(req)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		apierror.Abort(c, apierror.ImageTooLarge)
+		return false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return false
+	}
+	return true
+}
+
+// decodeImageData は AI に渡す image_data（Base64 の data URL）を読み、形式を確かめる。
+// 空なら nil。読めない画像なら image_data の入力エラーを返して false
+func decodeImageData(c *gin.Context, dataURL string) (*services.Image, bool) {
+	if dataURL == "" {
+		return nil, true
+	}
+	data, err := storage.DecodeDataURL(dataURL)
+	var contentType string
+	if err == nil {
+		contentType, _, err = storage.DetectImage(data)
+	}
+	switch {
+	case err == nil:
+		return &services.Image{MIMEType: contentType, Data: data}, true
+	case errors.Is(err, storage.ErrImageTooLarge):
+		apierror.Abort(c, apierror.ImageTooLarge)
+	default:
+		apierror.Abort(c, apierror.Invalid("image_data", apierror.RuleImage))
+	}
+	return nil, false
+}
+
+// respondImageError は saveImage のエラーをレスポンスにする。エラーが無ければ true
+func (h *Handler) respondImageError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrImageTooLarge):
+		apierror.Abort(c, apierror.ImageTooLarge)
+	case errors.Is(err, storage.ErrUnsupportedImage):
+		apierror.Abort(c, apierror.UnsupportedImage)
+	default:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	}
+	return false
+}
+
+// withImageURL はストレージに移行済みの商品の image_url を配信URLにする
+func (h *Handler) withImageURL(p *models.Product) {
+	if p.ImageKey != "" {
+		p.ImageURL = h.Storage.URL(p.ImageKey)
+	}
+}
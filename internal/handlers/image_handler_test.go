@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/storage"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pngBytes は 1x1 の PNG
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// upload は data を multipart の image フィールドで uid として送る
+func (s *testServer) upload(uid string, data []byte) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("image", "photo.png")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/images", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+idToken(s.t, uid))
+	w := httptest.NewRecorder()
+	s.r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadAndServeImage(t *testing.T) {
+	s := newTestServer(t)
+	data := pngBytes(t)
+
+	w := s.upload("alice", data)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d: %s", w.Code, w.Body.String())
+	}
+	var img struct {
+		ImageKey string `json:"image_key"`
+		ImageURL string `json:"image_url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &img)
+	if !strings.HasSuffix(img.ImageKey, ".png") || img.ImageURL != "/api/images/"+img.ImageKey {
+		t.Fatalf("uploaded = %+v", img)
+	}
+
+	w = s.do("GET", img.ImageURL, "", nil)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("serve = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// 出品時に image_key を渡すと、商品の image_url は配信URLになる
+	var p struct {
+		ImageURL string `json:"image_url"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/products", "alice", map[string]any{"title": "with image", "price": 500, "image_key": img.ImageKey}, &p)
+	if p.ImageURL != img.ImageURL {
+		t.Fatalf("product image_url = %q, want %q", p.ImageURL, img.ImageURL)
+	}
+}
+
+func TestUploadImageRejects(t *testing.T) {
+	s := newTestServer(t)
+	if w := s.upload("alice", []byte("not an image")); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("text upload = %d, want 415", w.Code)
+	}
+	s.expectError(http.StatusBadRequest, "image_required", "POST", "/api/images", "alice", map[string]any{})
+}
+
+// expectFieldError は1項目の入力エラーとそのルールを確かめる
+func (s *testServer) expectFieldError(field, rule, method, path, uid string, body any) {
+	s.t.Helper()
+	var resp struct {
+		Code   string `json:"code"`
+		Fields []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"fields"`
+	}
+	s.expect(http.StatusBadRequest, method, path, uid, body, &resp)
+	if resp.Code != "validation_failed" || len(resp.Fields) != 1 || resp.Fields[0].Field != field || resp.Fields[0].Code != rule {
+		s.t.Fatalf("%s %s: error = %+v, want %s %s", method, path, resp, field, rule)
+	}
+}
+
+func TestProductImageKey(t *testing.T) {
+	s := newTestServer(t)
+	var img struct {
+		ImageKey string `json:"image_key"`
+	}
+	w := s.upload("alice", pngBytes(t))
+	json.Unmarshal(w.Body.Bytes(), &img)
+	if !strings.HasPrefix(img.ImageKey, "products/alice/") {
+		t.Fatalf("image_key = %q, want under products/alice/", img.ImageKey)
+	}
+
+	// 他人のアップロードした画像や、形式の不正なキー、存在しない画像は指定できない
+	for _, key := range []string{
+		img.ImageKey,
+		"products/bob/../alice/x.png",
+		"/etc/passwd",
+		"products/bob",
+	} {
+		s.expectFieldError("image_key", "not_owned", "POST", "/api/products", "bob", map[string]any{"title": "x", "price": 500, "image_key": key})
+	}
+	s.expectFieldError("image_key", "not_found", "POST", "/api/products", "bob", map[string]any{"title": "x", "price": 500, "image_key": "products/bob/missing.png"})
+
+	// 編集でも同じ。今の画像のキーを送り返すのは認める
+	id := s.createProduct("bob", 1000)
+	path := fmt.Sprintf("/api/products/%d", id)
+	s.expectFieldError("image_key", "not_owned", "PATCH", path, "bob", map[string]any{"image_key": img.ImageKey, "version": 1})
+
+	var p models.Product
+	s.expect(http.StatusCreated, "POST", "/api/products", "alice", map[string]any{"title": "x", "price": 500, "image_key": img.ImageKey}, &p)
+	s.expect(http.StatusOK, "PATCH", fmt.Sprintf("/api/products/%d", p.ID), "alice", map[string]any{"title": "y", "image_key": img.ImageKey, "version": 1}, &p)
+	if p.ImageKey != img.ImageKey {
+		t.Fatalf("image_key after update = %q", p.ImageKey)
+	}
+}
+
+func TestProductImageDataURL(t *testing.T) {
+	s := newTestServer(t)
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngBytes(t))
+
+	var p models.Product
+	s.expect(http.StatusCreated, "POST", "/api/products", "alice", map[string]any{"title": "x", "price": 500, "image_url": dataURL}, &p)
+	if !strings.HasPrefix(p.ImageKey, "products/alice/") {
+		t.Fatalf("image_key = %q", p.ImageKey)
+	}
+
+	// 上限を超える画像はデコードする前に、ボディの上限を超えるものは読み切る前に断る
+	tooLarge := "data:image/png;base64," + strings.Repeat("A", base64.StdEncoding.EncodedLen(storage.MaxImageSize)+4)
+	s.expectError(http.StatusRequestEntityTooLarge, "image_too_large", "POST", "/api/products", "alice", map[string]any{"title": "x", "price": 500, "image_url": tooLarge})
+	s.expectError(http.StatusRequestEntityTooLarge, "image_too_large", "POST", "/api/products", "alice", map[string]any{"title": "x", "price": 500, "image_url": tooLarge + strings.Repeat("A", 128<<10)})
+	s.expectError(http.StatusBadRequest, "image_unreadable", "POST", "/api/products", "alice", map[string]any{"title": "x", "price": 500, "image_url": "data:image/png;base64,!!"})
+}
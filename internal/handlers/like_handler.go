@@ -1,57 +1,165 @@
 package handlers
 
 import (
-	"backend/internal/db"
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/events"
 	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ToggleLike: いいねの登録と解除を切り替える
-func ToggleLike(c *gin.Context) {
-	var l models.Like
-	if err := c.ShouldBindJSON(&l); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストです"})
+func (h *Handler) ToggleLike(c *gin.Context) {
+	var req ToggleLikeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	p, ok := h.requireProduct(c, "product_id", req.ProductID)
+	if !ok {
 		return
 	}
 
-	// すでにいいねしているか確認
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND product_id = ?)"
-	err := db.DB.QueryRow(query, l.UserID, l.ProductID).Scan(&exists)
+	uid := auth.UID(c)
+	liked, err := h.Likes.Toggle(c.Request.Context(), uid, req.ProductID)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.Invalid("product_id", apierror.RuleNotFound))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースエラー"})
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
-
-	if exists {
-		// すでにある場合は削除（解除）
-		_, err = db.DB.Exec("DELETE FROM likes WHERE user_id = ? AND product_id = ?", l.UserID, l.ProductID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "解除に失敗しました"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"status": "unliked", "is_liked": false})
-	} else {
-		// ない場合は挿入（登録）
-		_, err = db.DB.Exec("INSERT INTO likes (user_id, product_id) VALUES (?, ?)", l.UserID, l.ProductID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "登録に失敗しました"})
-			return
-		}
+	if liked {
+		h.Events.Publish(c.Request.Context(), events.Event{Type: events.ProductLiked, ActorID: uid, RecipientID: p.SellerID, ProductID: p.ID})
 		c.JSON(http.StatusOK, gin.H{"status": "liked", "is_liked": true})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"status": "unliked", "is_liked": false})
+	}
+}
+
+// LikeProduct: PUT /products/:id/like。すでにいいね済みでも同じ結果を返す（連打や再送で状態が反転しない）
+func (h *Handler) LikeProduct(c *gin.Context) {
+	p, ok := h.likeTarget(c)
+	if !ok {
+		return
+	}
+	uid := auth.UID(c)
+	added, err := h.Likes.Like(c.Request.Context(), uid, p.ID)
+	if !likeWriteOK(c, err) {
+		return
+	}
+	// 出品者への通知は新しくいいねされたときだけ
+	if added {
+		h.Events.Publish(c.Request.Context(), events.Event{Type: events.ProductLiked, ActorID: uid, RecipientID: p.SellerID, ProductID: p.ID})
 	}
+	c.JSON(http.StatusOK, gin.H{"status": "liked", "is_liked": true})
 }
 
-// CheckLikeStatus: フロントエンド表示時に「いいね済」かどうかを判定する
-func CheckLikeStatus(c *gin.Context) {
-	userID := c.Query("user_id")
-	productID := c.Query("product_id")
+// UnlikeProduct: DELETE /products/:id/like。いいねしていなくても成功を返す
+func (h *Handler) UnlikeProduct(c *gin.Context) {
+	p, ok := h.likeTarget(c)
+	if !ok {
+		return
+	}
+	if _, err := h.Likes.Unlike(c.Request.Context(), auth.UID(c), p.ID); !likeWriteOK(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "unliked", "is_liked": false})
+}
 
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND product_id = ?)"
-	db.DB.QueryRow(query, userID, productID).Scan(&exists)
+// likeTarget はパスの :id の商品を読む。無ければ 404
+func (h *Handler) likeTarget(c *gin.Context) (*models.Product, bool) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return nil, false
+	}
+	p, err := h.Products.Get(c.Request.Context(), id)
+	if !likeWriteOK(c, err) {
+		return nil, false
+	}
+	return p, true
+}
 
+// likeWriteOK は err を応答に変える。商品が無い（読んだ後に削除された場合も含む）なら 404
+func likeWriteOK(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.ProductNotFound)
+	default:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	}
+	return false
+}
+
+// CheckLikeStatus: フロントエンド表示時に、ログイン中のユーザーが「いいね済」かどうかを判定する。未ログインなら false
+func (h *Handler) CheckLikeStatus(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Query("product_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParam.With("product_id"))
+		return
+	}
+	uid := auth.UID(c)
+	if uid == "" {
+		c.JSON(http.StatusOK, gin.H{"is_liked": false})
+		return
+	}
+
+	exists, err := h.Likes.Exists(c.Request.Context(), uid, productID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"is_liked": exists})
-}
\ No newline at end of file
+}
+
+// GetLikeStatuses: 商品カードごとに GET /likes/status を呼ばずに、まとめていいね数と状態を返す。
+// 未ログインなら liked_by_me はすべて false。削除済みや存在しない商品は items に含めない
+func (h *Handler) GetLikeStatuses(c *gin.Context) {
+	var req LikeStatusesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	statuses, err := h.Likes.Statuses(c.Request.Context(), auth.UID(c), req.ProductIDs)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	// 問い合わせの順に並べる（同じIDは1回だけ）
+	items := make([]models.LikeStatus, 0, len(statuses))
+	for _, id := range req.ProductIDs {
+		if st, ok := statuses[id]; ok {
+			items = append(items, st)
+			delete(statuses, id)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// withLikedByMe は uid がログインしていれば、各商品の liked_by_me を1回の問い合わせで埋める
+func (h *Handler) withLikedByMe(ctx context.Context, uid string, products []models.Product) error {
+	if uid == "" || len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	statuses, err := h.Likes.Statuses(ctx, uid, ids)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		liked := statuses[products[i].ID].LikedByMe
+		products[i].LikedByMe = &liked
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestToggleLike(t *testing.T) {
+	s := newTestServer(t)
+	id := s.createProduct("alice", 1000)
+
+	var resp struct {
+		IsLiked bool `json:"is_liked"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/likes/toggle", "bob", map[string]any{"product_id": id}, &resp)
+	if !resp.IsLiked {
+		t.Fatal("first toggle did not like")
+	}
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/likes/status?product_id=%d", id), "bob", nil, &resp)
+	if !resp.IsLiked {
+		t.Fatal("status after like = false")
+	}
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/likes/status?product_id=%d", id), "", nil, &resp)
+	if resp.IsLiked {
+		t.Fatal("anonymous status = true")
+	}
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/likes/status?product_id=abc", "bob", nil)
+
+	var p models.Product
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/products/%d", id), "bob", nil, &p)
+	if p.LikeCount != 1 || p.LikedByMe == nil || !*p.LikedByMe {
+		t.Fatalf("product after like = %+v", p)
+	}
+
+	s.expect(http.StatusOK, "POST", "/api/likes/toggle", "bob", map[string]any{"product_id": id}, &resp)
+	if resp.IsLiked {
+		t.Fatal("second toggle did not unlike")
+	}
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/likes/toggle", "bob", map[string]any{"product_id": 999})
+}
+
+func TestLikeStatuses(t *testing.T) {
+	s := newTestServer(t)
+	a, b := s.createProduct("alice", 1000), s.createProduct("alice", 2000)
+	s.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/products/%d/like", b), "bob", nil, nil)
+
+	var resp struct {
+		Items []models.LikeStatus `json:"items"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/likes/status", "bob", map[string]any{"product_ids": []int{b, a, 999, b}}, &resp)
+	want := []models.LikeStatus{{ProductID: b, LikeCount: 1, LikedByMe: true}, {ProductID: a}}
+	if fmt.Sprint(resp.Items) != fmt.Sprint(want) {
+		t.Fatalf("statuses = %+v, want %+v", resp.Items, want)
+	}
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/likes/status", "", map[string]any{"product_ids": []int{}})
+}
+
+// TestLikeIsIdempotent は PUT/DELETE を繰り返してもいいね数が二重に増減しないことを確かめる
+func TestLikeIsIdempotent(t *testing.T) {
+	s := newTestServer(t)
+	id := s.createProduct("alice", 1000)
+	like := fmt.Sprintf("/api/products/%d/like", id)
+
+	count := func() int {
+		t.Helper()
+		var p models.Product
+		s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/products/%d", id), "", nil, &p)
+		return p.LikeCount
+	}
+	for range 2 {
+		s.expect(http.StatusOK, "PUT", like, "bob", nil, nil)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("like_count after PUT twice = %d, want 1", n)
+	}
+	for range 2 {
+		s.expect(http.StatusOK, "DELETE", like, "bob", nil, nil)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("like_count after DELETE twice = %d, want 0", n)
+	}
+}
@@ -1,86 +1,107 @@
 package handlers
 
 import (
-	"backend/internal/db"
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/events"
+	"backend/internal/metrics"
 	"backend/internal/models"
-	"fmt"
+	"backend/internal/realtime"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // メッセージ送信
-func SendMessage(c *gin.Context) {
-	var m models.Message
-	if err := c.ShouldBindJSON(&m); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストのJSONパースに失敗: " + err.Error()})
+func (h *Handler) SendMessage(c *gin.Context) {
+	var req SendMessageRequest
+	if !bindJSON(c, &req) {
 		return
 	}
-
-	result, err := db.DB.Exec(
-		"INSERT INTO messages (product_id, sender_id, receiver_id, content) VALUES (?, ?, ?, ?)",
-		m.ProductID, m.SenderID, m.ReceiverID, m.Content,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "DBへのINSERTに失敗: " + err.Error()})
+	m := models.Message{ProductID: req.ProductID, SenderID: auth.UID(c), ReceiverID: req.ReceiverID, Content: req.Content}
+	if m.ReceiverID == m.SenderID {
+		apierror.Abort(c, apierror.Invalid("receiver_id", apierror.RuleSelf))
+		return
+	}
+	if _, ok := h.requireProduct(c, "product_id", m.ProductID); !ok || !h.requireUser(c, "receiver_id", m.ReceiverID) {
 		return
 	}
 
-	lastID, err := result.LastInsertId()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "LastInsertId取得に失敗: " + err.Error()})
+	if err := h.Messages.Create(c.Request.Context(), &m); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
-	m.ID = int(lastID)
+	metrics.MessagesSent.Inc()
+	// 保存は済んでいるので、配信に失敗してもエラーにはしない（相手は再接続時に取り直せる）
+	if err := h.Hub.PublishMessage(c.Request.Context(), m); err != nil {
+		h.Logger.WarnContext(c.Request.Context(), "message publish failed", "message_id", m.ID, "error", err)
+	}
+	h.Events.Publish(c.Request.Context(), events.Event{
+		Type: events.MessageSent, ActorID: m.SenderID, RecipientID: m.ReceiverID,
+		ProductID: m.ProductID, MessageID: m.ID, Text: m.Content,
+	})
 	c.JSON(http.StatusCreated, m)
 }
 
-// 特定の商品・ユーザー間のチャット履歴取得
-func GetChatHistory(c *gin.Context) {
-	productIDStr := c.Query("product_id")
-	user1 := c.Query("user1")
-	user2 := c.Query("user2")
-
-	// 型情報をログ出力
-	logMsg := "[GetChatHistory] product_id: " + productIDStr + " (" + fmt.Sprintf("%T", productIDStr) + ") " +
-		"user1: " + user1 + " (" + fmt.Sprintf("%T", user1) + ") " +
-		"user2: " + user2 + " (" + fmt.Sprintf("%T", user2) + ")"
-	println(logMsg)
-
-	// product_idをintに変換
-	productID, err := strconv.Atoi(productIDStr)
+// GET /api/messages?product_id=&partner_id=
+// ログイン中のユーザーと partner_id の間の、その商品についてのチャット履歴を返す。
+// 2人の間の価格提示も現在の状態で混ぜ、WebSocket と同じ形のイベントを作成順に並べる
+func (h *Handler) GetChatHistory(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Query("product_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "product_idは数値で指定してください", "product_id": productIDStr, "type": fmt.Sprintf("%T", productIDStr)})
+		apierror.Abort(c, apierror.InvalidParam.With("product_id"))
+		return
+	}
+	me, partner := auth.UID(c), c.Query("partner_id")
+	if partner == "" || partner == me {
+		apierror.Abort(c, apierror.InvalidParam.With("partner_id"))
 		return
 	}
 
-	rows, err := db.DB.Query(`
-		   SELECT id, product_id, sender_id, receiver_id, content, created_at 
-		   FROM messages 
-		   WHERE product_id = ? 
-		   AND ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?))
-		   ORDER BY created_at ASC`,
-		productID, user1, user2, user2, user1,
-	)
+	messages, err := h.Messages.ListThread(c.Request.Context(), productID, me, partner)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "DBからのSELECTに失敗: " + err.Error(), "product_id": productID, "product_id_type": fmt.Sprintf("%T", productID), "user1": user1, "user2": user2})
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
-	defer rows.Close()
+	offers, err := h.Offers.ListByProduct(c.Request.Context(), productID, me)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	offers = threadOffers(offers, partner, time.Now())
+
+	history := make([]realtime.Event, 0, len(messages)+len(offers))
+	for i := range messages {
+		history = append(history, realtime.Event{Type: realtime.EventMessage, Message: &messages[i]})
+	}
+	for i := range offers {
+		history = append(history, realtime.Event{Type: realtime.EventOffer, Offer: &offers[i]})
+	}
+	slices.SortStableFunc(history, func(a, b realtime.Event) int { return eventTime(a).Compare(eventTime(b)) })
+	c.JSON(http.StatusOK, history)
+}
 
-	var messages []models.Message
-	for rows.Next() {
-		var m models.Message
-		if err := rows.Scan(&m.ID, &m.ProductID, &m.SenderID, &m.ReceiverID, &m.Content, &m.CreatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "行のScanに失敗: " + err.Error()})
-			return
+// threadOffers は商品の価格提示のうち partner との間のものを、期限切れを反映して返す。
+// 出品者として見ているときは、別の買い手との提示を除く
+func threadOffers(offers []models.Offer, partner string, now time.Time) []models.Offer {
+	kept := offers[:0]
+	for _, o := range offers {
+		if o.RoleOf(partner) == "" {
+			continue
 		}
-		messages = append(messages, m)
+		o.Refresh(now)
+		kept = append(kept, o)
 	}
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "rows.Err(): " + err.Error()})
-		return
+	return kept
+}
+
+// eventTime はチャット履歴の並び順に使う作成日時
+func eventTime(e realtime.Event) time.Time {
+	if e.Offer != nil {
+		return e.Offer.CreatedAt
 	}
-	c.JSON(http.StatusOK, messages)
+	return e.Message.CreatedAt
 }
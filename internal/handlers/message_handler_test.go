@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestMessagesAndConversations(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	id := s.createProduct("alice", 1000)
+
+	send := func(from, to, content string) models.Message {
+		t.Helper()
+		var m models.Message
+		s.expect(http.StatusCreated, "POST", "/api/messages", from, map[string]any{"product_id": id, "receiver_id": to, "content": content}, &m)
+		return m
+	}
+	send("bob", "alice", "is this available?")
+	last := send("alice", "bob", "yes")
+	send("bob", "alice", "great")
+
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/messages", "bob", map[string]any{"product_id": id, "receiver_id": "bob", "content": "me"})
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/messages", "bob", map[string]any{"product_id": id, "receiver_id": "nobody", "content": "hi"})
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/messages", "bob", map[string]any{"product_id": 999, "receiver_id": "alice", "content": "hi"})
+
+	// 履歴は自分と partner_id の間のものだけ
+	var thread []realtime.Event
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/messages?product_id=%d&partner_id=bob", id), "alice", nil, &thread)
+	if len(thread) != 3 || thread[0].Type != realtime.EventMessage || thread[0].Message.Content != "is this available?" {
+		t.Fatalf("alice-bob thread = %+v", thread)
+	}
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/messages?product_id=%d&partner_id=alice", id), "carol", nil, &thread)
+	if len(thread) != 0 {
+		t.Fatalf("carol-alice thread = %+v", thread)
+	}
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", fmt.Sprintf("/api/messages?product_id=%d", id), "alice", nil)
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/messages?product_id=abc&partner_id=bob", "alice", nil)
+
+	var inbox []models.Conversation
+	s.expect(http.StatusOK, "GET", "/api/conversations", "alice", nil, &inbox)
+	if len(inbox) != 1 || inbox[0].Partner.ID != "bob" || inbox[0].UnreadCount != 2 || inbox[0].LastMessage.Content != "great" {
+		t.Fatalf("alice's inbox = %+v", inbox)
+	}
+
+	// last_read_id までを既読にし、省略すると最新まで既読にする
+	read := fmt.Sprintf("/api/conversations/%d/bob/read", id)
+	s.expect(http.StatusNoContent, "POST", read, "alice", map[string]any{"last_read_id": last.ID}, nil)
+	s.expect(http.StatusOK, "GET", "/api/conversations", "alice", nil, &inbox)
+	if inbox[0].UnreadCount != 1 {
+		t.Fatalf("unread after partial read = %d, want 1", inbox[0].UnreadCount)
+	}
+	s.expect(http.StatusNoContent, "POST", read, "alice", nil, nil)
+	s.expect(http.StatusOK, "GET", "/api/conversations", "alice", nil, &inbox)
+	if inbox[0].UnreadCount != 0 {
+		t.Fatalf("unread after read = %d, want 0", inbox[0].UnreadCount)
+	}
+}
+
+func TestChatHistoryIncludesOffers(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	s.createUser("carol")
+	id := s.createProduct("alice", 1000)
+	offers := fmt.Sprintf("/api/products/%d/offers", id)
+
+	s.expect(http.StatusCreated, "POST", "/api/messages", "bob", map[string]any{"product_id": id, "receiver_id": "alice", "content": "値下げできますか"}, nil)
+	var first models.Offer
+	s.expect(http.StatusCreated, "POST", offers, "bob", map[string]any{"price": 800}, &first)
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/offers/%d/counter", first.ID), "alice", map[string]any{"price": 900}, nil)
+	s.expect(http.StatusCreated, "POST", "/api/messages", "alice", map[string]any{"product_id": id, "receiver_id": "bob", "content": "900円なら"}, nil)
+	s.expect(http.StatusCreated, "POST", offers, "carol", map[string]any{"price": 700}, nil)
+
+	// 価格提示は現在の状態で作成順に混ざる。別の買い手との提示は含まない
+	var thread []realtime.Event
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/messages?product_id=%d&partner_id=bob", id), "alice", nil, &thread)
+	var got []string
+	for _, e := range thread {
+		switch e.Type {
+		case realtime.EventMessage:
+			got = append(got, e.Message.Content)
+		case realtime.EventOffer:
+			got = append(got, fmt.Sprintf("%d %s", e.Offer.Price, e.Offer.Status))
+		}
+	}
+	want := []string{"値下げできますか", "800 countered", "900 pending", "900円なら"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("history = %q, want %q", got, want)
+	}
+
+	// 買い手からも同じ会話が見える
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/messages?product_id=%d&partner_id=alice", id), "bob", nil, &thread)
+	if len(thread) != 4 {
+		t.Fatalf("bob's history = %+v", thread)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10 // pong の期限より前に ping を送る
+	wsBuffer     = 64
+)
+
+// 認証はクエリのトークンで行い Cookie を使わないので、どのオリジンからの接続も受け付ける
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(*http.Request) bool { return true },
+}
+
+// --- チャットのリアルタイム配信 ---
+// GET /api/messages/ws?product_id=&partner_id=&last_id=&access_token=
+// ログイン中のユーザーと partner_id の間の、その商品についてのメッセージを push する。
+// last_id を渡すと、それより後のメッセージを先に送ってから新着の配信に移る。
+// 2人の間の価格提示は接続のたびに現在の状態をすべて送り、以後は変化のたびに送る
+func (h *Handler) ChatSocket(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Query("product_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParam.With("product_id"))
+		return
+	}
+	me, partner := auth.UID(c), c.Query("partner_id")
+	if partner == "" || partner == me {
+		apierror.Abort(c, apierror.InvalidParam.With("partner_id"))
+		return
+	}
+	lastID := 0
+	if s := c.Query("last_id"); s != "" {
+		if lastID, err = strconv.Atoi(s); err != nil || lastID < 0 {
+			apierror.Abort(c, apierror.InvalidParam.With("last_id"))
+			return
+		}
+	}
+
+	if _, err := h.Products.Get(c.Request.Context(), productID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Abort(c, apierror.ProductNotFound)
+			return
+		}
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade がエラーレスポンスを書いている
+		return
+	}
+	defer conn.Close()
+
+	// 先に購読してから取りこぼし分を読むので、その間に送られたメッセージも漏れない
+	sub := h.Hub.Subscribe(realtime.Thread{ProductID: productID, A: me, B: partner}, wsBuffer)
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), wsWriteWait)
+	backlog, err := h.Messages.ListThreadAfter(ctx, productID, me, partner, lastID)
+	var offers []models.Offer
+	if err == nil {
+		offers, err = h.Offers.ListByProduct(ctx, productID, me)
+	}
+	cancel()
+	if err != nil {
+		h.Logger.ErrorContext(c.Request.Context(), "chat backlog failed", "product_id", productID, "error", err)
+		closeSocket(conn, websocket.CloseInternalServerErr, "履歴の取得に失敗しました")
+		return
+	}
+
+	// 読み取り側は pong を受けて期限を延ばすだけ。切断を検知したら done を閉じる
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(e realtime.Event) bool {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(e) == nil
+	}
+
+	for i := range backlog {
+		if !send(realtime.Event{Type: realtime.EventMessage, Message: &backlog[i]}) {
+			return
+		}
+		lastID = backlog[i].ID
+	}
+	offers = threadOffers(offers, partner, time.Now())
+	for i := range offers {
+		if !send(realtime.Event{Type: realtime.EventOffer, Offer: &offers[i]}) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-sub.Events:
+			if !ok {
+				// 送信が追いつかず購読が切れた。クライアントは last_id を付けて再接続する
+				closeSocket(conn, websocket.CloseTryAgainLater, "再接続してください")
+				return
+			}
+			if e.Message != nil {
+				if e.Message.ID <= lastID {
+					continue // 履歴として送信済み
+				}
+				lastID = e.Message.ID
+			}
+			if !send(e) {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func closeSocket(conn *websocket.Conn, code int, text string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteWait))
+}
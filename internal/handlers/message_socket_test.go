@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"backend/internal/realtime"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestChatSocket(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	id := s.createProduct("alice", 1000)
+	s.expect(http.StatusCreated, "POST", "/api/messages", "bob", map[string]any{"product_id": id, "receiver_id": "alice", "content": "before"}, nil)
+
+	srv := httptest.NewServer(s.r)
+	defer srv.Close()
+	url := fmt.Sprintf("%s/api/messages/ws?product_id=%d&partner_id=bob&access_token=%s", strings.Replace(srv.URL, "http", "ws", 1), id, idToken(t, "alice"))
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// 接続時に履歴が届き、その後の新着が push される
+	var e realtime.Event
+	if err := conn.ReadJSON(&e); err != nil || e.Type != realtime.EventMessage || e.Message.Content != "before" {
+		t.Fatalf("backlog = %+v, %v", e, err)
+	}
+	s.expect(http.StatusCreated, "POST", "/api/messages", "bob", map[string]any{"product_id": id, "receiver_id": "alice", "content": "after"}, nil)
+	if err := conn.ReadJSON(&e); err != nil || e.Message == nil || e.Message.Content != "after" {
+		t.Fatalf("pushed = %+v, %v", e, err)
+	}
+}
+
+func TestChatSocketRejects(t *testing.T) {
+	s := newTestServer(t)
+	id := s.createProduct("alice", 1000)
+	tok := idToken(t, "alice")
+
+	s.expectError(http.StatusUnauthorized, "invalid_token", "GET", "/api/messages/ws?product_id=1&partner_id=bob&access_token=bogus", "", nil)
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", fmt.Sprintf("/api/messages/ws?product_id=%d&partner_id=alice&access_token=%s", id, tok), "", nil)
+	s.expectError(http.StatusNotFound, "product_not_found", "GET", "/api/messages/ws?product_id=999&partner_id=bob&access_token="+tok, "", nil)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- 通知一覧 ---
+// ?limit=&cursor=&unread=true（未読だけ）。新しい順に返し、unread_count は条件に関係なく数える
+func (h *Handler) GetNotifications(c *gin.Context) {
+	q := repository.NotificationQuery{Cursor: c.Query("cursor")}
+	var ok bool
+	if q.Limit, ok = parseLimit(c); !ok {
+		return
+	}
+	if v := c.Query("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParam.With("unread"))
+			return
+		}
+		q.UnreadOnly = unread
+	}
+
+	page, err := h.Notifications.List(c.Request.Context(), auth.UID(c), q)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		apierror.Abort(c, apierror.InvalidParam.With("cursor"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// --- 指定した通知を既読にする ---
+func (h *Handler) MarkNotificationsRead(c *gin.Context) {
+	var req MarkNotificationsReadRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := h.Notifications.MarkRead(c.Request.Context(), auth.UID(c), req.IDs); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// --- すべての通知を既読にする ---
+func (h *Handler) MarkAllNotificationsRead(c *gin.Context) {
+	if err := h.Notifications.MarkRead(c.Request.Context(), auth.UID(c), nil); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// --- 通知設定 ---
+// チャネル (in_app / email / webpush) ごとに、種類 (like / message / purchase) を受け取るかどうか
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	prefs, err := h.Notifications.Preferences(c.Request.Context(), auth.UID(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// UpdateNotificationPreferences は送った項目だけを変え、変更後の設定全体を返す
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	var req UpdateNotificationPreferencesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx, uid := c.Request.Context(), auth.UID(c)
+	if err := h.Notifications.SetPreferences(ctx, uid, req.Preferences); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	prefs, err := h.Notifications.Preferences(ctx, uid)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// --- ブラウザのプッシュ通知 ---
+// フロントエンドは public_key を applicationServerKey にして購読し、結果をそのまま登録する
+func (h *Handler) GetPushKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"enabled": h.PushPublicKey != "", "public_key": h.PushPublicKey})
+}
+
+func (h *Handler) SubscribePush(c *gin.Context) {
+	var req PushSubscriptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	sub := models.PushSubscription{UserID: auth.UID(c), Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := h.PushSubscriptions.Save(c.Request.Context(), &sub); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (h *Handler) UnsubscribePush(c *gin.Context) {
+	var req PushUnsubscribeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := h.PushSubscriptions.Delete(c.Request.Context(), auth.UID(c), req.Endpoint); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/notify"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// newNotifyingServer はイベントをアプリ内通知にする Notifier をつないだサーバーを作る
+func newNotifyingServer(t *testing.T) (*testServer, *events.Bus) {
+	t.Helper()
+	bus := events.NewBus()
+	s := newTestServer(t, func(d *Deps) {
+		n := notify.New(d.Repositories, d.Logger, notify.NewInApp(d.Notifications))
+		bus.Subscribe(n.Handle)
+		d.Events = bus
+	})
+	return s, bus
+}
+
+func TestNotifications(t *testing.T) {
+	s, bus := newNotifyingServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	id := s.createProduct("alice", 1000)
+
+	// 通知は購読者のゴルーチンで作られるので、順番を決めるため1件ずつ待つ
+	s.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/products/%d/like", id), "bob", nil, nil)
+	if err := bus.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s.expect(http.StatusCreated, "POST", "/api/messages", "bob", map[string]any{"product_id": id, "receiver_id": "alice", "content": "hi"}, nil)
+	if err := bus.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var page models.NotificationPage
+	s.expect(http.StatusOK, "GET", "/api/notifications", "alice", nil, &page)
+	if len(page.Items) != 2 || page.UnreadCount != 2 {
+		t.Fatalf("notifications = %+v", page)
+	}
+	if page.Items[0].Kind != models.NotifyMessage || page.Items[1].Kind != models.NotifyLike {
+		t.Fatalf("kinds = %s, %s", page.Items[0].Kind, page.Items[1].Kind)
+	}
+
+	s.expect(http.StatusNoContent, "POST", "/api/notifications/read", "alice", map[string]any{"ids": []int{page.Items[1].ID}}, nil)
+	s.expect(http.StatusOK, "GET", "/api/notifications?unread=true", "alice", nil, &page)
+	if len(page.Items) != 1 || page.UnreadCount != 1 {
+		t.Fatalf("unread after marking one = %+v", page)
+	}
+	s.expect(http.StatusNoContent, "POST", "/api/notifications/read-all", "alice", nil, nil)
+	s.expect(http.StatusOK, "GET", "/api/notifications", "alice", nil, &page)
+	if page.UnreadCount != 0 {
+		t.Fatalf("unread after read-all = %d", page.UnreadCount)
+	}
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/notifications?unread=maybe", "alice", nil)
+
+	// 他人の通知は見えない
+	s.expect(http.StatusOK, "GET", "/api/notifications", "bob", nil, &page)
+	if len(page.Items) != 0 {
+		t.Fatalf("bob's notifications = %+v", page.Items)
+	}
+}
+
+func TestLikeNotificationsNotRepeated(t *testing.T) {
+	s, bus := newNotifyingServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	id := s.createProduct("alice", 1000)
+
+	// いいねの付け外しを繰り返しても、出品者への通知は1件。切り替えの API でも同じ
+	like := fmt.Sprintf("/api/products/%d/like", id)
+	toggle := map[string]any{"product_id": id}
+	steps := []struct {
+		method, path string
+		body         any
+	}{
+		{"PUT", like, nil},
+		{"DELETE", like, nil},
+		{"PUT", like, nil},
+		{"POST", "/api/likes/toggle", toggle},
+		{"POST", "/api/likes/toggle", toggle},
+	}
+	for _, step := range steps {
+		s.expect(http.StatusOK, step.method, step.path, "bob", step.body, nil)
+		if err := bus.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var page models.NotificationPage
+	s.expect(http.StatusOK, "GET", "/api/notifications", "alice", nil, &page)
+	if len(page.Items) != 1 || page.Items[0].Kind != models.NotifyLike {
+		t.Fatalf("notifications = %+v", page.Items)
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	s, bus := newNotifyingServer(t)
+	s.createUser("alice")
+	id := s.createProduct("alice", 1000)
+
+	var resp struct {
+		Preferences models.NotificationPreferences `json:"preferences"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/notifications/preferences", "alice", nil, &resp)
+	if !resp.Preferences.Enabled(models.ChannelInApp, models.NotifyLike) || resp.Preferences.Enabled(models.ChannelEmail, models.NotifyLike) {
+		t.Fatalf("default preferences = %+v", resp.Preferences)
+	}
+
+	off := map[string]any{"preferences": map[string]any{"in_app": map[string]any{"like": false}}}
+	s.expect(http.StatusOK, "PUT", "/api/notifications/preferences", "alice", off, &resp)
+	if resp.Preferences.Enabled(models.ChannelInApp, models.NotifyLike) || !resp.Preferences.Enabled(models.ChannelInApp, models.NotifyMessage) {
+		t.Fatalf("updated preferences = %+v", resp.Preferences)
+	}
+
+	// 切った種類の通知は届かない
+	s.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/products/%d/like", id), "bob", nil, nil)
+	bus.Wait(context.Background())
+	var page models.NotificationPage
+	s.expect(http.StatusOK, "GET", "/api/notifications", "alice", nil, &page)
+	if len(page.Items) != 0 {
+		t.Fatalf("notifications with likes off = %+v", page.Items)
+	}
+
+	s.expectError(http.StatusBadRequest, "validation_failed", "PUT", "/api/notifications/preferences", "alice",
+		map[string]any{"preferences": map[string]any{"sms": map[string]any{"like": true}}})
+}
+
+func TestPushSubscriptions(t *testing.T) {
+	s := newTestServer(t)
+	var key struct {
+		Enabled bool `json:"enabled"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/notifications/push/key", "", nil, &key)
+	if key.Enabled {
+		t.Fatal("push enabled without a VAPID key")
+	}
+
+	sub := map[string]any{
+		"endpoint": "https://fcm.googleapis.com/fcm/send/abc",
+		"keys":     map[string]any{"p256dh": "BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM", "auth": "tBHItJI5svbpez7KI4CCXg"},
+	}
+	s.expect(http.StatusCreated, "POST", "/api/notifications/push/subscriptions", "alice", sub, nil)
+	s.expect(http.StatusNoContent, "DELETE", "/api/notifications/push/subscriptions", "alice", map[string]any{"endpoint": sub["endpoint"]}, nil)
+
+	// サーバーから POST する先なので、既知のプッシュサービス以外は受け付けない
+	for _, endpoint := range []string{
+		"http://fcm.googleapis.com/fcm/send/abc",
+		"https://127.0.0.1/push",
+		"https://169.254.169.254/latest/meta-data",
+		"https://internal.example.com/push",
+		"https://fcm.googleapis.com.evil.example/push",
+		"https://fcm.googleapis.com:8443/fcm/send/abc",
+		"https://user@fcm.googleapis.com/fcm/send/abc",
+	} {
+		sub["endpoint"] = endpoint
+		s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/notifications/push/subscriptions", "alice", sub)
+	}
+	for _, endpoint := range []string{
+		"https://updates.push.services.mozilla.com/wpush/v2/abc",
+		"https://web.push.apple.com/abc",
+		"https://wns2-par02p.notify.windows.com/w/?token=abc",
+	} {
+		sub["endpoint"] = endpoint
+		s.expect(http.StatusCreated, "POST", "/api/notifications/push/subscriptions", "alice", sub, nil)
+	}
+}
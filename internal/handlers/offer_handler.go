@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultOfferTTL は価格提示の返答期限と、承諾後に購入できる期限の既定値
+const DefaultOfferTTL = 24 * time.Hour
+
+func (h *Handler) offerTTL() time.Duration {
+	if h.OfferTTL > 0 {
+		return h.OfferTTL
+	}
+	return DefaultOfferTTL
+}
+
+// --- 価格の提示（買い手） ---
+// 出品者が承諾すると、期限までその買い手だけが提示価格で購入できる
+func (h *Handler) CreateOffer(c *gin.Context) {
+	productID, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
+	var req OfferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	o := models.Offer{ProductID: productID, BuyerID: auth.UID(c), Price: req.Price, ExpiresAt: time.Now().Add(h.offerTTL())}
+	err := h.Offers.Create(c.Request.Context(), &o)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.ProductNotFound)
+	case errors.Is(err, repository.ErrSelfPurchase):
+		apierror.Abort(c, apierror.SelfOffer)
+	case errors.Is(err, repository.ErrSoldOut):
+		apierror.Abort(c, apierror.SoldOut)
+	case errors.Is(err, repository.ErrWithdrawn):
+		apierror.Abort(c, apierror.ProductWithdrawn)
+	case errors.Is(err, repository.ErrAlreadyExists):
+		apierror.Abort(c, apierror.OfferPending)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	default:
+		h.publishOffer(c.Request.Context(), o)
+		c.JSON(http.StatusCreated, o)
+	}
+}
+
+// --- 商品の価格提示一覧 ---
+// 出品者には全員分、それ以外には自分が買い手の提示だけを古い順に返す
+func (h *Handler) GetProductOffers(c *gin.Context) {
+	productID, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
+	offers, err := h.Offers.ListByProduct(c.Request.Context(), productID, auth.UID(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	now := time.Now()
+	for i := range offers {
+		offers[i].Refresh(now)
+	}
+	c.JSON(http.StatusOK, offers)
+}
+
+// --- 価格提示の詳細（買い手・出品者のみ） ---
+func (h *Handler) GetOffer(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.OfferNotFound)
+		return
+	}
+	o, err := h.Offers.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && o.RoleOf(auth.UID(c)) == "") {
+		apierror.Abort(c, apierror.OfferNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	o.Refresh(time.Now())
+	c.JSON(http.StatusOK, o)
+}
+
+// --- 価格提示への返答 ---
+// 承諾・拒否・提示し返しは提示された側、取り消しは提示した本人が行う
+func (h *Handler) AcceptOffer(c *gin.Context) { h.replyOffer(c, models.OfferAccept, 0) }
+func (h *Handler) RejectOffer(c *gin.Context) { h.replyOffer(c, models.OfferReject, 0) }
+func (h *Handler) CancelOffer(c *gin.Context) { h.replyOffer(c, models.OfferCancel, 0) }
+
+// CounterOffer は別の価格を提示し返す。元の提示は countered になり、新しい提示を返す
+func (h *Handler) CounterOffer(c *gin.Context) {
+	var req OfferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	h.replyOffer(c, models.OfferCounter, req.Price)
+}
+
+func (h *Handler) replyOffer(c *gin.Context, action models.OfferAction, price int) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.OfferNotFound)
+		return
+	}
+
+	reply := repository.OfferReply{Action: action, Price: price, ExpiresAt: time.Now().Add(h.offerTTL())}
+	updated, next, err := h.Offers.Respond(c.Request.Context(), id, auth.UID(c), reply)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.OfferNotFound)
+	case errors.Is(err, repository.ErrInvalidTransition):
+		apierror.Abort(c, apierror.OfferClosed)
+	case errors.Is(err, repository.ErrForbidden):
+		apierror.Abort(c, apierror.OfferForbidden)
+	case errors.Is(err, repository.ErrSoldOut):
+		apierror.Abort(c, apierror.SoldOut)
+	case errors.Is(err, repository.ErrWithdrawn):
+		apierror.Abort(c, apierror.ProductWithdrawn)
+	case errors.Is(err, repository.ErrReserved):
+		apierror.Abort(c, apierror.ProductReserved)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	case next != nil:
+		h.publishOffer(c.Request.Context(), *updated)
+		h.publishOffer(c.Request.Context(), *next)
+		c.JSON(http.StatusCreated, next)
+	default:
+		h.publishOffer(c.Request.Context(), *updated)
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// publishOffer は価格提示の変化を会話スレッドに流す。保存は済んでいるので失敗しても続ける
+func (h *Handler) publishOffer(ctx context.Context, o models.Offer) {
+	if err := h.Hub.PublishOffer(ctx, o); err != nil {
+		h.Logger.WarnContext(ctx, "offer publish failed", "offer_id", o.ID, "error", err)
+	}
+}
+
+// RunOfferExpiry は interval ごとに期限切れの価格提示を expired にし、会話スレッドに通知する。
+// ctx が終わるまで戻らない
+func (h *Handler) RunOfferExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		expired, err := h.Offers.Expire(ctx)
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "offer expiry failed", "error", err)
+			continue
+		}
+		for _, o := range expired {
+			h.publishOffer(ctx, o)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestOfferNegotiation(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	id := s.createProduct("alice", 1000)
+	offers := fmt.Sprintf("/api/products/%d/offers", id)
+
+	s.expectError(http.StatusBadRequest, "self_offer", "POST", offers, "alice", map[string]any{"price": 800})
+	var first models.Offer
+	s.expect(http.StatusCreated, "POST", offers, "bob", map[string]any{"price": 800}, &first)
+	if first.Status != models.OfferPending || first.ProposedBy != models.RoleBuyer {
+		t.Fatalf("offer = %+v", first)
+	}
+	s.expectError(http.StatusConflict, "offer_pending", "POST", offers, "bob", map[string]any{"price": 850})
+
+	// 提示した本人は承諾できず、当事者以外には見えない
+	s.expectError(http.StatusForbidden, "offer_forbidden", "POST", fmt.Sprintf("/api/offers/%d/accept", first.ID), "bob", nil)
+	s.expectError(http.StatusNotFound, "offer_not_found", "GET", fmt.Sprintf("/api/offers/%d", first.ID), "carol", nil)
+
+	// 出品者が提示し返し、買い手が承諾する
+	var counter models.Offer
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/offers/%d/counter", first.ID), "alice", map[string]any{"price": 900}, &counter)
+	if counter.ParentID == nil || *counter.ParentID != first.ID || counter.ProposedBy != models.RoleSeller {
+		t.Fatalf("counter = %+v", counter)
+	}
+	s.expectError(http.StatusConflict, "offer_closed", "POST", fmt.Sprintf("/api/offers/%d/accept", first.ID), "alice", nil)
+	var accepted models.Offer
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/offers/%d/accept", counter.ID), "bob", nil, &accepted)
+	if accepted.Status != models.OfferAccepted {
+		t.Fatalf("accepted = %+v", accepted)
+	}
+
+	var list []models.Offer
+	s.expect(http.StatusOK, "GET", offers, "alice", nil, &list)
+	if len(list) != 2 {
+		t.Fatalf("seller's offers = %+v", list)
+	}
+	s.expect(http.StatusOK, "GET", offers, "carol", nil, &list)
+	if len(list) != 0 {
+		t.Fatalf("other user's offers = %+v", list)
+	}
+
+	// 承諾された買い手だけが承諾価格で買える
+	s.expectError(http.StatusConflict, "product_reserved", "POST", fmt.Sprintf("/api/products/%d/purchase", id), "carol", nil)
+	var o models.Order
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", id), "bob", nil, &o)
+	if o.Price != 900 {
+		t.Fatalf("order price = %d, want 900", o.Price)
+	}
+
+	// 注文を取り消すと、使われた提示も終わった状態になる
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/orders/%d/status", o.ID), "bob", map[string]any{"status": "cancelled"}, nil)
+	var got models.Offer
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/offers/%d", counter.ID), "bob", nil, &got)
+	if got.Status != models.OfferCancelled {
+		t.Fatalf("offer after order cancel = %s, want cancelled", got.Status)
+	}
+}
+
+func TestCancelAndRejectOffer(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	id := s.createProduct("alice", 1000)
+	offers := fmt.Sprintf("/api/products/%d/offers", id)
+
+	var o models.Offer
+	s.expect(http.StatusCreated, "POST", offers, "bob", map[string]any{"price": 700}, &o)
+	s.expectError(http.StatusForbidden, "offer_forbidden", "POST", fmt.Sprintf("/api/offers/%d/cancel", o.ID), "alice", nil)
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/offers/%d/cancel", o.ID), "bob", nil, &o)
+	if o.Status != models.OfferCancelled {
+		t.Fatalf("cancelled = %+v", o)
+	}
+
+	s.expect(http.StatusCreated, "POST", offers, "bob", map[string]any{"price": 750}, &o)
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/offers/%d/reject", o.ID), "alice", nil, &o)
+	if o.Status != models.OfferRejected {
+		t.Fatalf("rejected = %+v", o)
+	}
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", offers, "bob", map[string]any{"price": 100})
+}
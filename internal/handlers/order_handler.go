@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/events"
+	"backend/internal/metrics"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultPaymentTTL は購入から支払いまでの期限の既定値
+const DefaultPaymentTTL = 72 * time.Hour
+
+func (h *Handler) paymentTTL() time.Duration {
+	if h.PaymentTTL > 0 {
+		return h.PaymentTTL
+	}
+	return DefaultPaymentTTL
+}
+
+// --- 商品購入（注文の作成） ---
+func (h *Handler) PurchaseProduct(c *gin.Context) {
+	productID, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
+
+	order, err := h.Orders.Purchase(c.Request.Context(), productID, auth.UID(c))
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.ProductNotFound)
+	case errors.Is(err, repository.ErrSelfPurchase):
+		apierror.Abort(c, apierror.SelfPurchase)
+	case errors.Is(err, repository.ErrSoldOut):
+		apierror.Abort(c, apierror.SoldOut)
+	case errors.Is(err, repository.ErrWithdrawn):
+		apierror.Abort(c, apierror.ProductWithdrawn)
+	case errors.Is(err, repository.ErrReserved):
+		apierror.Abort(c, apierror.ProductReserved)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	default:
+		metrics.Purchases.Inc()
+		h.Events.Publish(c.Request.Context(), events.Event{
+			Type: events.ProductPurchased, ActorID: order.BuyerID, RecipientID: order.SellerID,
+			ProductID: order.ProductID, OrderID: order.ID, Price: order.Price,
+		})
+		c.JSON(http.StatusCreated, order)
+	}
+}
+
+// --- 自分の注文一覧 (?role=buyer|seller、省略時は両方) ---
+func (h *Handler) GetMyOrders(c *gin.Context) {
+	role := models.OrderRole(c.Query("role"))
+	if role != "" && role != models.RoleBuyer && role != models.RoleSeller {
+		apierror.Abort(c, apierror.InvalidParam.With("role"))
+		return
+	}
+
+	orders, err := h.Orders.ListByUser(c.Request.Context(), auth.UID(c), role)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// --- 注文詳細（買い手・売り手のみ） ---
+func (h *Handler) GetOrderByID(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.OrderNotFound)
+		return
+	}
+	o, err := h.Orders.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && o.RoleOf(auth.UID(c)) == "") {
+		apierror.Abort(c, apierror.OrderNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// --- 注文ステータスの更新 ---
+func (h *Handler) UpdateOrderStatus(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.OrderNotFound)
+		return
+	}
+	var req UpdateOrderStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	order, err := h.Orders.Transition(c.Request.Context(), id, auth.UID(c), req.Status)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.OrderNotFound)
+	case errors.Is(err, repository.ErrInvalidTransition):
+		apierror.Abort(c, apierror.InvalidTransition)
+	case errors.Is(err, repository.ErrConflict):
+		apierror.Abort(c, apierror.OrderConflict)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	default:
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// RunPaymentExpiry は interval ごとに支払い期限を過ぎた注文をキャンセルし、商品を再び購入できるようにする。
+// 買い手が支払わないまま商品を押さえ続けられないようにする。ctx が終わるまで戻らない
+func (h *Handler) RunPaymentExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		expired, err := h.Orders.ExpireUnpaid(ctx, time.Now().Add(-h.paymentTTL()))
+		if err != nil {
+			h.Logger.ErrorContext(ctx, "payment expiry failed", "error", err)
+			continue
+		}
+		for _, o := range expired {
+			h.Logger.InfoContext(ctx, "unpaid order cancelled", "order_id", o.ID, "product_id", o.ProductID)
+		}
+	}
+}
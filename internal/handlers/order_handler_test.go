@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPurchaseAndOrderFlow(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	id := s.createProduct("alice", 1000)
+
+	s.expectError(http.StatusBadRequest, "self_purchase", "POST", fmt.Sprintf("/api/products/%d/purchase", id), "alice", nil)
+	var o models.Order
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", id), "bob", nil, &o)
+	if o.BuyerID != "bob" || o.SellerID != "alice" || o.Price != 1000 || o.Status != models.OrderPendingPayment {
+		t.Fatalf("order = %+v", o)
+	}
+	s.expectError(http.StatusConflict, "sold_out", "POST", fmt.Sprintf("/api/products/%d/purchase", id), "carol", nil)
+
+	// 注文は当事者にしか見えない
+	path := fmt.Sprintf("/api/orders/%d", o.ID)
+	s.expect(http.StatusOK, "GET", path, "alice", nil, nil)
+	s.expectError(http.StatusNotFound, "order_not_found", "GET", path, "carol", nil)
+
+	var orders []models.Order
+	s.expect(http.StatusOK, "GET", "/api/orders?role=buyer", "bob", nil, &orders)
+	if len(orders) != 1 {
+		t.Fatalf("bob's orders = %+v", orders)
+	}
+	s.expect(http.StatusOK, "GET", "/api/orders?role=buyer", "alice", nil, &orders)
+	if len(orders) != 0 {
+		t.Fatalf("alice's orders as buyer = %+v", orders)
+	}
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/orders?role=admin", "alice", nil)
+
+	// 完了前は評価できない。遷移はそれぞれの立場でしか進められない
+	s.expectError(http.StatusConflict, "order_not_completed", "POST", path+"/review", "bob", map[string]any{"rating": "good"})
+	s.expectError(http.StatusConflict, "invalid_transition", "POST", path+"/status", "alice", map[string]any{"status": "paid"})
+	for _, step := range []struct {
+		uid    string
+		status models.OrderStatus
+	}{
+		{"bob", models.OrderPaid},
+		{"alice", models.OrderShipped},
+		{"bob", models.OrderDelivered},
+		{"bob", models.OrderCompleted},
+	} {
+		s.expect(http.StatusOK, "POST", path+"/status", step.uid, map[string]any{"status": step.status}, &o)
+		if o.Status != step.status {
+			t.Fatalf("status = %s, want %s", o.Status, step.status)
+		}
+	}
+
+	var r models.Review
+	s.expect(http.StatusCreated, "POST", path+"/review", "bob", map[string]any{"rating": "good", "comment": "thanks"}, &r)
+	if r.RevieweeID != "alice" || r.ReviewerRole != models.RoleBuyer {
+		t.Fatalf("review = %+v", r)
+	}
+	s.expectError(http.StatusConflict, "already_reviewed", "POST", path+"/review", "bob", map[string]any{"rating": "bad"})
+
+	var reviews struct {
+		Summary models.RatingSummary `json:"summary"`
+		Reviews []models.Review      `json:"reviews"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/users/alice/reviews", "", nil, &reviews)
+	if reviews.Summary.Good != 1 || reviews.Summary.Total != 1 || len(reviews.Reviews) != 1 {
+		t.Fatalf("alice's reviews = %+v", reviews)
+	}
+}
+
+func TestCancelledOrderRelistsProduct(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	id := s.createProduct("alice", 1000)
+
+	var o models.Order
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", id), "bob", nil, &o)
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/orders/%d/status", o.ID), "alice", map[string]any{"status": "cancelled"}, nil)
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", id), "carol", nil, nil)
+}
+
+func TestRefundedOrderRelistsProduct(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	id := s.createProduct("alice", 1000)
+
+	var o models.Order
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", id), "bob", nil, &o)
+	path := fmt.Sprintf("/api/orders/%d/status", o.ID)
+	s.expect(http.StatusOK, "POST", path, "bob", map[string]any{"status": "paid"}, nil)
+	s.expect(http.StatusOK, "POST", path, "alice", map[string]any{"status": "refunded"}, nil)
+
+	var p models.Product
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/products/%d", id), "", nil, &p)
+	if p.IsSold {
+		t.Fatal("refunded product is still sold")
+	}
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", id), "carol", nil, nil)
+}
+
+func TestUnpaidOrderExpires(t *testing.T) {
+	s := newTestServer(t, func(d *Deps) { d.PaymentTTL = time.Millisecond })
+	s.createUser("alice")
+	unpaid := s.createProduct("alice", 1000)
+	paid := s.createProduct("alice", 2000)
+
+	var o, p models.Order
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", unpaid), "bob", nil, &o)
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", paid), "bob", nil, &p)
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/orders/%d/status", p.ID), "bob", map[string]any{"status": "paid"}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.h.RunPaymentExpiry(ctx, 10*time.Millisecond)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// 支払い期限を過ぎた注文はキャンセルされ、商品はまた買える
+	deadline := time.Now().Add(5 * time.Second)
+	for o.Status != models.OrderCancelled {
+		if time.Now().After(deadline) {
+			t.Fatalf("unpaid order status = %s", o.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/orders/%d", o.ID), "bob", nil, &o)
+	}
+	s.expect(http.StatusCreated, "POST", fmt.Sprintf("/api/products/%d/purchase", unpaid), "carol", nil, nil)
+
+	// 支払い済みの注文はそのまま
+	s.expect(http.StatusOK, "GET", fmt.Sprintf("/api/orders/%d", p.ID), "bob", nil, &p)
+	if p.Status != models.OrderPaid {
+		t.Fatalf("paid order status = %s", p.Status)
+	}
+}
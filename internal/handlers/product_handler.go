@@ -1,120 +1,354 @@
 package handlers
 
 import (
-	"backend/internal/db"
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/metrics"
 	"backend/internal/models"
+	"backend/internal/repository"
 	"backend/internal/services"
-	"github.com/gin-gonic/gin"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
 )
 
 // --- 商品一覧取得 ---
-func GetProducts(c *gin.Context) {
-	// image_url カラムには Base64 文字列が入っている想定でそのまま取得
-	rows, err := db.DB.Query("SELECT id, seller_id, title, price, image_url, is_sold FROM products ORDER BY created_at DESC")
+// ?limit=&cursor=&sort=newest|price_asc|price_desc|most_liked&min_price=&max_price=&sold=true|false&seller_id=
+func (h *Handler) GetProducts(c *gin.Context) {
+	q, ok := parseProductQuery(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.Products.List(c.Request.Context(), q)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		apierror.Abort(c, apierror.InvalidParam.With("cursor"))
+		return
+	}
+	if err == nil {
+		err = h.withLikedByMe(c.Request.Context(), auth.UID(c), page.Items)
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	for i := range page.Items {
+		h.withImageURL(&page.Items[i])
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// --- 商品検索 ---
+// ?q=検索語（空白区切りで複数可）&limit=&cursor=&min_price=&max_price=&sold=
+// 関連度の高い順に返し、一致箇所をハイライトする
+func (h *Handler) SearchProducts(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		apierror.Abort(c, apierror.SearchQueryRequired)
+		return
+	}
+	// 並び順は関連度で固定なので sort や seller_id は読まない
+	q := repository.ProductQuery{Cursor: c.Query("cursor")}
+	var ok bool
+	if q.Limit, ok = parseLimit(c); !ok {
+		return
+	}
+	if !parseProductFilters(c, &q) {
+		return
+	}
+
+	page, err := h.Products.Search(c.Request.Context(), text, q)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		apierror.Abort(c, apierror.InvalidParam.With("cursor"))
+		return
+	}
+	if err == nil {
+		err = h.withLikedByMe(c.Request.Context(), auth.UID(c), page.Items)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "商品一覧の取得に失敗しました"})
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
-	defer rows.Close()
 
-	var products []models.Product
-	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.SellerID, &p.Title, &p.Price, &p.ImageURL, &p.IsSold); err != nil {
-			continue
+	terms := strings.Fields(text)
+	result := models.SearchPage{Items: make([]models.SearchHit, 0, len(page.Items)), NextCursor: page.NextCursor}
+	for _, p := range page.Items {
+		h.withImageURL(&p)
+		result.Items = append(result.Items, models.SearchHit{
+			Product: p,
+			Highlight: models.SearchHighlight{
+				Title:       highlight(p.Title, terms),
+				Description: snippet(p.Description, terms),
+			},
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parseLimit は ?limit= を読む。省略時は defaultPageSize、不正な値なら400を返して false
+func parseLimit(c *gin.Context) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return defaultPageSize, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxPageSize {
+		apierror.Abort(c, apierror.InvalidParam.With("limit"))
+		return 0, false
+	}
+	return n, true
+}
+
+// parseProductQuery は一覧の検索条件を読む。不正な値なら400を返して false
+func parseProductQuery(c *gin.Context) (repository.ProductQuery, bool) {
+	q := repository.ProductQuery{
+		Sort:     repository.ProductSort(c.DefaultQuery("sort", string(repository.SortNewest))),
+		Cursor:   c.Query("cursor"),
+		SellerID: c.Query("seller_id"),
+	}
+	// 出品者本人が自分の商品を一覧するときは取り下げ中のものも含める
+	q.IncludeWithdrawn = q.SellerID != "" && q.SellerID == auth.UID(c)
+	switch q.Sort {
+	case repository.SortNewest, repository.SortPriceAsc, repository.SortPriceDesc, repository.SortMostLiked:
+	default:
+		apierror.Abort(c, apierror.InvalidParam.With("sort"))
+		return q, false
+	}
+
+	var ok bool
+	if q.Limit, ok = parseLimit(c); !ok {
+		return q, false
+	}
+	return q, parseProductFilters(c, &q)
+}
+
+// parseProductFilters は価格と売り切れの絞り込みを q に読み込む。不正な値なら400を返して false
+func parseProductFilters(c *gin.Context, q *repository.ProductQuery) bool {
+	for name, dst := range map[string]**int{"min_price": &q.MinPrice, "max_price": &q.MaxPrice} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				apierror.Abort(c, apierror.InvalidParam.With(name))
+				return false
+			}
+			*dst = &n
+		}
+	}
+	if v := c.Query("sold"); v != "" {
+		sold, err := strconv.ParseBool(v)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParam.With("sold"))
+			return false
 		}
-		products = append(products, p)
+		q.Sold = &sold
 	}
-	c.JSON(http.StatusOK, products)
+	return true
 }
 
 // --- 商品詳細取得 ---
-func GetProductByID(c *gin.Context) {
-	id := c.Param("id")
-	var p models.Product
-	err := db.DB.QueryRow("SELECT id, seller_id, title, description, price, image_url, is_sold FROM products WHERE id = ?", id).
-		Scan(&p.ID, &p.SellerID, &p.Title, &p.Description, &p.Price, &p.ImageURL, &p.IsSold)
-	
+func (h *Handler) GetProductByID(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
+	p, err := h.Products.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "商品が見つかりませんでした"})
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
+	if uid := auth.UID(c); uid != "" {
+		liked, err := h.Likes.Exists(c.Request.Context(), uid, p.ID)
+		if err != nil {
+			apierror.Abort(c, apierror.Internal.Wrap(err))
+			return
+		}
+		p.LikedByMe = &liked
+	}
+	h.withImageURL(p)
 	c.JSON(http.StatusOK, p)
 }
 
 // --- 新規出品 ---
-func CreateProduct(c *gin.Context) {
-	var p models.Product
-	if err := c.ShouldBindJSON(&p); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエスト形式が正しくありません"})
+func (h *Handler) CreateProduct(c *gin.Context) {
+	var req CreateProductRequest
+	if !bindImageJSON(c, &req) {
 		return
 	}
+	// 出品者はリクエストボディではなく検証済みトークンから決める
+	p := models.Product{
+		SellerID:    auth.UID(c),
+		Title:       req.Title,
+		Description: req.Description,
+		Price:       req.Price,
+		ImageKey:    req.ImageKey,
+	}
 
-	// p.ImageURL にはフロントエンドから送られてきた Base64 文字列が入っている
-	_, err := db.DB.Exec("INSERT INTO products (seller_id, title, description, price, image_url) VALUES (?, ?, ?, ?, ?)",
-		p.SellerID, p.Title, p.Description, p.Price, p.ImageURL)
-	
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースへの保存に失敗しました: " + err.Error()})
+	// 画像は /api/images でアップロードして image_key を送るのが基本。
+	// 以前のフロントエンドのように image_url に Base64 が来た場合はここでストレージに移す
+	if strings.HasPrefix(req.ImageURL, "data:") {
+		key, ok := h.saveDataURL(c, p.SellerID, req.ImageURL)
+		if !ok {
+			return
+		}
+		p.ImageKey = key
+	} else if p.ImageKey != "" && !h.checkImageKey(c, p.SellerID, p.ImageKey) {
 		return
 	}
+
+	if err := h.Products.Create(c.Request.Context(), &p); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	metrics.ProductsCreated.Inc()
+	h.withImageURL(&p)
 	c.JSON(http.StatusCreated, p)
 }
 
-// --- 商品購入（売り切れ更新） ---
-func PurchaseProduct(c *gin.Context) {
-	id := c.Param("id")
-	_, err := db.DB.Exec("UPDATE products SET is_sold = TRUE WHERE id = ?", id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "購入処理に失敗しました"})
+// --- 商品の編集（出品者のみ） ---
+// 変更する項目だけを送る。version には取得時の値を入れ、他のタブなどで先に
+// 更新されていたら409を返す
+func (h *Handler) UpdateProduct(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
+	var req UpdateProductRequest
+	if !bindImageJSON(c, &req) {
+		return
+	}
+
+	uid := auth.UID(c)
+	patch := repository.ProductPatch{Title: req.Title, Description: req.Description, Price: req.Price, ImageKey: req.ImageKey}
+	if strings.HasPrefix(req.ImageURL, "data:") {
+		key, ok := h.saveDataURL(c, uid, req.ImageURL)
+		if !ok {
+			return
+		}
+		patch.ImageKey = &key
+	} else if req.ImageKey != nil && *req.ImageKey != "" {
+		// 今の画像のキーを送り返すのは、移行前の形式のキーでもそのまま認める
+		if !h.keepsImageKey(c, id, *req.ImageKey) && !h.checkImageKey(c, uid, *req.ImageKey) {
+			return
+		}
+	}
+
+	p, err := h.Products.Update(c.Request.Context(), id, auth.UID(c), req.Version, patch)
+	if !respondProductWriteError(c, err) {
+		return
+	}
+	h.withImageURL(p)
+	c.JSON(http.StatusOK, p)
+}
+
+// keepsImageKey は key が商品の今の画像と同じかどうか
+func (h *Handler) keepsImageKey(c *gin.Context, id int, key string) bool {
+	p, err := h.Products.Get(c.Request.Context(), id)
+	return err == nil && p.ImageKey == key
+}
+
+// --- 取り下げ・再出品（出品者のみ） ---
+func (h *Handler) WithdrawProduct(c *gin.Context) { h.setWithdrawn(c, true) }
+func (h *Handler) RelistProduct(c *gin.Context)   { h.setWithdrawn(c, false) }
+
+func (h *Handler) setWithdrawn(c *gin.Context, withdrawn bool) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "purchased"})
+	p, err := h.Products.SetWithdrawn(c.Request.Context(), id, auth.UID(c), withdrawn)
+	if !respondProductWriteError(c, err) {
+		return
+	}
+	h.withImageURL(p)
+	c.JSON(http.StatusOK, p)
+}
+
+// --- 削除（出品者のみ、論理削除） ---
+func (h *Handler) DeleteProduct(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.ProductNotFound)
+		return
+	}
+	if !respondProductWriteError(c, h.Products.Delete(c.Request.Context(), id, auth.UID(c))) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondProductWriteError は出品者による更新系のエラーをレスポンスにする。エラーが無ければ true
+func respondProductWriteError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.ProductNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		apierror.Abort(c, apierror.NotOwner)
+	case errors.Is(err, repository.ErrSoldOut):
+		apierror.Abort(c, apierror.ProductSold)
+	case errors.Is(err, repository.ErrConflict):
+		apierror.Abort(c, apierror.VersionConflict)
+	default:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+	}
+	return false
 }
 
 // --- AI商品説明生成 (ここが重要！) ---
-func GenerateAIDescription(c *gin.Context) {
-    var req struct {
-        Title     string `json:"title"`
-        ImageData string `json:"image_data"` // フロントから受け取る名前
-    }
-    
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(400, gin.H{"error": "JSON形式が不正です"})
-        return
-    }
-
-    // ここ！ req.ImageData を第2引数に渡す
-    desc, err := services.GenerateDescription(req.Title, req.ImageData)
-    if err != nil {
-        c.JSON(500, gin.H{
-            "error": "Geminiエラー詳細: " + err.Error(),
-        }) 
-        return
-    }
-    c.JSON(200, gin.H{"description": desc})
+func (h *Handler) GenerateAIDescription(c *gin.Context) {
+	var req AIDescriptionRequest
+	if !bindImageJSON(c, &req) {
+		return
+	}
+	img, ok := decodeImageData(c, req.ImageData)
+	if !ok {
+		return
+	}
+
+	desc, err := h.AI.GenerateDescription(c.Request.Context(), req.Title, img)
+	if err != nil {
+		apierror.Abort(c, apierror.AIFailed.Wrap(err))
+		return
+	}
+	c.JSON(200, gin.H{"description": desc})
 }
 
 // --- AI価格査定 ---
-// --- AI商品説明生成 ---
-func SuggestAIPrice(c *gin.Context) {
-    var req struct {
-        Title       string `json:"title"`
-        Description string `json:"description"`
-        ImageData   string `json:"image_data"` // 価格査定にも画像を使うように拡張
-    }
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "入力内容が不足しています"})
-        return
-    }
-
-    // ここも ImageData を渡せるように services.SuggestPrice を呼ぶ
-    price, err := services.SuggestPrice(req.Title, req.Description, req.ImageData)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "価格査定に失敗しました: " + err.Error()})
-        return
-    }
-
-    c.JSON(http.StatusOK, gin.H{"suggestion": price})
+func (h *Handler) SuggestAIPrice(c *gin.Context) {
+	var req SuggestPriceRequest
+	if !bindImageJSON(c, &req) {
+		return
+	}
+	img, ok := decodeImageData(c, req.ImageData)
+	if !ok {
+		return
+	}
+
+	suggestion, err := h.AI.SuggestPrice(c.Request.Context(), req.Title, req.Description, img)
+	if errors.Is(err, services.ErrMalformedAIResponse) {
+		apierror.Abort(c, apierror.AIMalformedResponse.Wrap(err))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.AIFailed.Wrap(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
 }
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/internal/storage"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProductLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+
+	var p models.Product
+	s.expect(http.StatusCreated, "POST", "/api/products", "alice", map[string]any{"title": "camera", "description": "old", "price": 5000}, &p)
+	if p.SellerID != "alice" || p.Version != 1 {
+		t.Fatalf("created = %+v", p)
+	}
+	path := fmt.Sprintf("/api/products/%d", p.ID)
+
+	var got models.Product
+	s.expect(http.StatusOK, "GET", path, "", nil, &got)
+	if got.Title != "camera" || got.LikedByMe != nil {
+		t.Fatalf("anonymous GET = %+v", got)
+	}
+
+	var page models.ProductPage
+	s.expect(http.StatusOK, "GET", "/api/products", "", nil, &page)
+	if len(page.Items) != 1 || page.Items[0].ID != p.ID {
+		t.Fatalf("list = %+v", page)
+	}
+
+	// 出品者以外は変更できず、古い version は競合になる
+	s.expectError(http.StatusForbidden, "not_owner", "PATCH", path, "bob", map[string]any{"price": 4000, "version": 1})
+	s.expect(http.StatusOK, "PATCH", path, "alice", map[string]any{"price": 4000, "version": 1}, &got)
+	if got.Price != 4000 || got.Version != 2 {
+		t.Fatalf("updated = %+v", got)
+	}
+	s.expectError(http.StatusConflict, "version_conflict", "PATCH", path, "alice", map[string]any{"price": 3000, "version": 1})
+
+	// 出品を止めると一覧から消え、再出品で戻る
+	s.expect(http.StatusOK, "POST", path+"/withdraw", "alice", nil, &got)
+	if !got.IsWithdrawn {
+		t.Fatalf("withdrawn = %+v", got)
+	}
+	s.expect(http.StatusOK, "GET", "/api/products", "", nil, &page)
+	if len(page.Items) != 0 {
+		t.Fatalf("list after withdraw = %+v", page.Items)
+	}
+	s.expectError(http.StatusConflict, "product_withdrawn", "POST", path+"/purchase", "bob", nil)
+	s.expect(http.StatusOK, "POST", path+"/relist", "alice", nil, &got)
+
+	s.expectError(http.StatusForbidden, "not_owner", "DELETE", path, "bob", nil)
+	s.expect(http.StatusNoContent, "DELETE", path, "alice", nil, nil)
+	s.expectError(http.StatusNotFound, "product_not_found", "GET", path, "", nil)
+}
+
+func TestCreateProductValidation(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"missing title", map[string]any{"price": 500}},
+		{"blank title", map[string]any{"title": "  ", "price": 500}},
+		{"price too low", map[string]any{"title": "x", "price": 299}},
+		{"price too high", map[string]any{"title": "x", "price": 10_000_000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.with(t).expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/products", "alice", tt.body)
+		})
+	}
+}
+
+func TestListProductsPagination(t *testing.T) {
+	s := newTestServer(t)
+	for i := range 3 {
+		s.createProduct("alice", 300+i*100)
+	}
+
+	var page models.ProductPage
+	s.expect(http.StatusOK, "GET", "/api/products?sort=price_asc&limit=2", "", nil, &page)
+	if len(page.Items) != 2 || page.Items[0].Price != 300 || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	s.expect(http.StatusOK, "GET", "/api/products?sort=price_asc&limit=2&cursor="+page.NextCursor, "", nil, &page)
+	if len(page.Items) != 1 || page.Items[0].Price != 500 || page.NextCursor != "" {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/products?sort=bogus", "", nil)
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/products?cursor=bogus", "", nil)
+}
+
+func TestSearchProducts(t *testing.T) {
+	s := newTestServer(t)
+	s.expect(http.StatusCreated, "POST", "/api/products", "alice", map[string]any{"title": "vintage camera", "price": 5000}, nil)
+	s.expect(http.StatusCreated, "POST", "/api/products", "alice", map[string]any{"title": "desk lamp", "price": 800}, nil)
+
+	var result struct {
+		Items []models.Product `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/products/search?q=camera", "", nil, &result)
+	if len(result.Items) != 1 || result.Items[0].Title != "vintage camera" {
+		t.Fatalf("search = %+v", result.Items)
+	}
+	s.expectError(http.StatusBadRequest, "search_query_required", "GET", "/api/products/search", "", nil)
+
+	// 並び順は関連度で固定なので、一覧用の sort は検証しない
+	s.expect(http.StatusOK, "GET", "/api/products/search?q=camera&sort=bogus", "", nil, &result)
+	s.expect(http.StatusOK, "GET", "/api/products/search?q=camera&max_price=1000", "", nil, &result)
+	if len(result.Items) != 0 {
+		t.Fatalf("search with max_price = %+v", result.Items)
+	}
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/products/search?q=camera&limit=0", "", nil)
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/products/search?q=camera&cursor=bogus", "", nil)
+}
+
+func TestAIDescription(t *testing.T) {
+	s := newTestServer(t)
+	s.llm.Script("素敵なカメラです")
+	var resp struct {
+		Description string `json:"description"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/ai/description", "alice", map[string]any{"title": "camera"}, &resp)
+	if resp.Description != "素敵なカメラです" {
+		t.Fatalf("description = %q", resp.Description)
+	}
+
+	s.llm.ScriptError(errors.New("quota exceeded"))
+	s.expectError(http.StatusBadGateway, "ai_failed", "POST", "/api/ai/description", "alice", map[string]any{"title": "camera"})
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/ai/description", "alice", map[string]any{"title": ""})
+}
+
+func TestAIImageData(t *testing.T) {
+	s := newTestServer(t)
+	png := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngBytes(t))
+
+	// 画像は中身から判定した形式で LLM に渡す
+	s.llm.Script("説明")
+	s.expect(http.StatusOK, "POST", "/api/ai/description", "alice", map[string]any{"title": "camera", "image_data": png}, nil)
+	img, ok := s.llm.Calls()[0].Parts[0].(services.Image)
+	if !ok || img.MIMEType != "image/png" {
+		t.Fatalf("parts = %+v", s.llm.Calls()[0].Parts)
+	}
+
+	// 読めない画像は黙って捨てずに入力エラーにする
+	for _, path := range []string{"/api/ai/description", "/api/ai/suggest-price"} {
+		for _, data := range []string{"data:image/png;base64,!!", "data:text/plain;base64,aGVsbG8=", "not a data url"} {
+			s.expectFieldError("image_data", "image", "POST", path, "alice", map[string]any{"title": "camera", "image_data": data})
+		}
+		tooLarge := "data:image/png;base64," + strings.Repeat("A", maxImageDataLen)
+		s.expectFieldError("image_data", "max_length", "POST", path, "alice", map[string]any{"title": "camera", "image_data": tooLarge})
+		s.expectError(http.StatusRequestEntityTooLarge, "image_too_large", "POST", path, "alice", map[string]any{"title": "camera", "image_data": tooLarge + strings.Repeat("A", 128<<10)})
+	}
+	if n := len(s.llm.Calls()); n != 1 {
+		t.Fatalf("LLM calls = %d, want 1", n)
+	}
+}
+
+// TestMaxImageDataLen は image_data のタグの上限が maxImageDataLen と合っていて、最大の画像が入ることを確かめる
+func TestMaxImageDataLen(t *testing.T) {
+	if need := base64.StdEncoding.EncodedLen(storage.MaxImageSize) + len("data:image/jpeg;base64,"); maxImageDataLen < need {
+		t.Fatalf("maxImageDataLen = %d, want at least %d", maxImageDataLen, need)
+	}
+	want := fmt.Sprintf("omitempty,max=%d", maxImageDataLen)
+	for _, req := range []any{AIDescriptionRequest{}, SuggestPriceRequest{}} {
+		f, _ := reflect.TypeOf(req).FieldByName("ImageData")
+		if got := f.Tag.Get("binding"); got != want {
+			t.Errorf("%T.ImageData binding = %q, want %q", req, got, want)
+		}
+	}
+}
+
+func TestAISuggestPrice(t *testing.T) {
+	s := newTestServer(t)
+	valid := `{"suggested_price":3000,"price_low":2500,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["x"]}`
+
+	// 1回目が不正でも、やり直しで正しければ成功する
+	s.llm.Script("not json", valid)
+	var got struct {
+		SuggestedPrice int `json:"suggested_price"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/ai/suggest-price", "alice", map[string]any{"title": "camera"}, &got)
+	if got.SuggestedPrice != 3000 {
+		t.Fatalf("suggestion = %+v", got)
+	}
+
+	s.llm.Script("not json", `{"suggested_price":-1}`)
+	s.expectError(http.StatusBadGateway, "ai_malformed_response", "POST", "/api/ai/suggest-price", "alice", map[string]any{"title": "camera"})
+	s.llm.ScriptError(errors.New("quota exceeded"))
+	s.expectError(http.StatusBadGateway, "ai_failed", "POST", "/api/ai/suggest-price", "alice", map[string]any{"title": "camera"})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/models"
+	"backend/internal/notify"
+	"backend/internal/repository"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+)
+
+// リクエストボディの型。models の構造体はDBとレスポンスの形なので、
+// 受け付ける項目と入力ルール（binding タグ）はここで別に定義する。
+// 価格は 300〜9,999,999 円、タイトルは100文字、説明は3000文字まで
+
+type CreateProductRequest struct {
+	Title       string `json:"title" binding:"required,notblank,max=100"`
+	Description string `json:"description" binding:"max=3000"`
+	Price       int    `json:"price" binding:"min=300,max=9999999"`
+	ImageKey    string `json:"image_key" binding:"max=255"`
+	ImageURL    string `json:"image_url"` // 以前のフロントエンドが送ってくる Base64 の data URL
+}
+
+// UpdateProductRequest は変更する項目だけを送る。Version は取得時の値
+type UpdateProductRequest struct {
+	Title       *string `json:"title" binding:"omitempty,notblank,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=3000"`
+	Price       *int    `json:"price" binding:"omitempty,min=300,max=9999999"`
+	ImageKey    *string `json:"image_key" binding:"omitempty,max=255"`
+	ImageURL    string  `json:"image_url"` // Base64 の data URL で画像を差し替える場合
+	Version     int     `json:"version" binding:"required,min=1"`
+}
+
+type SendMessageRequest struct {
+	ProductID  int    `json:"product_id" binding:"required,min=1"`
+	ReceiverID string `json:"receiver_id" binding:"required,max=128"`
+	Content    string `json:"content" binding:"required,notblank,max=1000"`
+}
+
+type ToggleLikeRequest struct {
+	ProductID int `json:"product_id" binding:"required,min=1"`
+}
+
+// LikeStatusesRequest は一覧に並んだ商品のいいねの状態をまとめて問い合わせる
+type LikeStatusesRequest struct {
+	ProductIDs []int `json:"product_ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+type SyncUserRequest struct {
+	Name      string `json:"name" binding:"max=50"`
+	AvatarURL string `json:"avatar_url" binding:"omitempty,url,max=2048"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status models.OrderStatus `json:"status" binding:"required,oneof=paid shipped delivered completed cancelled refunded"`
+}
+
+type SubmitReviewRequest struct {
+	Rating  models.Rating `json:"rating" binding:"required,oneof=good normal bad"`
+	Comment string        `json:"comment" binding:"max=1000"`
+}
+
+// OfferRequest は価格の提示と提示し返しに使う
+type OfferRequest struct {
+	Price int `json:"price" binding:"required,min=300,max=9999999"`
+}
+
+type MarkReadRequest struct {
+	LastReadID int `json:"last_read_id" binding:"min=0"` // 0 なら最新まで
+}
+
+type MarkNotificationsReadRequest struct {
+	IDs []int `json:"ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+// UpdateNotificationPreferencesRequest は変える項目だけを送る。例: {"preferences": {"email": {"like": true}}}
+type UpdateNotificationPreferencesRequest struct {
+	Preferences models.NotificationPreferences `json:"preferences" binding:"required,dive,keys,oneof=in_app email webpush,endkeys,dive,keys,oneof=like message purchase,endkeys"`
+}
+
+// PushSubscriptionRequest はブラウザの PushSubscription.toJSON() の形。
+// endpoint にはサーバーから POST するので、既知のプッシュサービスの URL だけを受け付ける
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,max=512,pushendpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required,base64rawurl,max=128"`
+		Auth   string `json:"auth" binding:"required,base64rawurl,max=64"`
+	} `json:"keys"`
+}
+
+type PushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,max=512"`
+}
+
+// maxImageDataLen は image_data（Base64 の data URL）の長さの上限。
+// storage.MaxImageSize を Base64 にした長さ (6990508) に "data:image/jpeg;base64," などの分を足したもの。
+// タグには定数を書けないので、値が合っていることはテストで確かめる
+const maxImageDataLen = 6990592
+
+type AIDescriptionRequest struct {
+	Title     string `json:"title" binding:"required,notblank,max=100"`
+	ImageData string `json:"image_data" binding:"omitempty,max=6990592"` // maxImageDataLen
+}
+
+type SuggestPriceRequest struct {
+	Title       string `json:"title" binding:"required,notblank,max=100"`
+	Description string `json:"description" binding:"max=3000"`
+	ImageData   string `json:"image_data" binding:"omitempty,max=6990592"` // maxImageDataLen
+}
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// エラーの項目名を Go のフィールド名ではなく JSON の名前にする
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("notblank", validators.NotBlank)
+	v.RegisterValidation("pushendpoint", func(fl validator.FieldLevel) bool {
+		return notify.IsPushEndpoint(fl.Field().String())
+	})
+}
+
+// bindJSON はボディを読んで入力ルールを確かめる。駄目なら400を返して false
+func bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return false
+	}
+	return true
+}
+
+// requireProduct は field で指定された商品が存在するかを確かめ、その商品を返す
+func (h *Handler) requireProduct(c *gin.Context, field string, id int) (*models.Product, bool) {
+	p, err := h.Products.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.Invalid(field, apierror.RuleNotFound))
+		return nil, false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return nil, false
+	}
+	return p, true
+}
+
+// requireUser は field で指定されたユーザーが存在するかを確かめる
+func (h *Handler) requireUser(c *gin.Context, field, id string) bool {
+	_, err := h.Users.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.Invalid(field, apierror.RuleNotFound))
+		return false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return false
+	}
+	return true
+}
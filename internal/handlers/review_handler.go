@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- 取引の評価（完了した注文の買い手・売り手がそれぞれ1回だけ） ---
+func (h *Handler) SubmitReview(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		apierror.Abort(c, apierror.OrderNotFound)
+		return
+	}
+	var req SubmitReviewRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	uid := auth.UID(c)
+	o, err := h.Orders.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && o.RoleOf(uid) == "") {
+		apierror.Abort(c, apierror.OrderNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	if o.Status != models.OrderCompleted {
+		apierror.Abort(c, apierror.OrderNotCompleted)
+		return
+	}
+
+	// 評価する相手は取引のもう一方
+	r := models.Review{OrderID: o.ID, ReviewerID: uid, Reviewer: models.UserSummary{ID: uid}, ReviewerRole: o.RoleOf(uid), Rating: req.Rating, Comment: req.Comment}
+	if r.ReviewerRole == models.RoleBuyer {
+		r.RevieweeID = o.SellerID
+	} else {
+		r.RevieweeID = o.BuyerID
+	}
+
+	err = h.Reviews.Create(c.Request.Context(), &r)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		apierror.Abort(c, apierror.AlreadyReviewed)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// --- ユーザーが受けた評価の一覧 (?role=buyer|seller で評価者の立場を絞り込む) ---
+func (h *Handler) GetUserReviews(c *gin.Context) {
+	role := models.OrderRole(c.Query("role"))
+	if role != "" && role != models.RoleBuyer && role != models.RoleSeller {
+		apierror.Abort(c, apierror.InvalidParam.With("role"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	uid := c.Param("uid")
+	reviews, err := h.Reviews.ListByReviewee(ctx, uid, role)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	summary, err := h.Reviews.Summary(ctx, uid)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"summary": summary, "reviews": reviews})
+}
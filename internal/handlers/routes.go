@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/auth"
+	"backend/internal/metrics"
+	"backend/internal/requestid"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes は /api 以下の全ルートを r に登録する。
+// main からも httptest からも同じルーティングを使えるようにここにまとめている。
+// timeouts はルートごとの処理時間の上限（RouteTimeouts で作る）
+func (h *Handler) RegisterRoutes(r gin.IRouter, verifier *auth.Verifier, timeouts map[string]time.Duration) {
+	api := r.Group("/api")
+	// エラーのレスポンスは apierror.Middleware がまとめて書く（認証エラーやタイムアウトも含む）
+	api.Use(requestid.Middleware(), apierror.Middleware(h.Logger), timeout(timeouts), auth.Middleware(verifier))
+	// ログイン必須のルート（ユーザーIDはトークンから取る）
+	authed := api.Group("", auth.RequireUser())
+
+	// --- ヘルスチェック ---
+	api.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	// --- 商品関連 (Products) ---
+	api.GET("/products", h.GetProducts)
+	api.GET("/products/search", h.SearchProducts)
+	api.GET("/products/:id", h.GetProductByID)
+	authed.POST("/products", h.CreateProduct)
+	authed.PATCH("/products/:id", h.UpdateProduct)
+	authed.DELETE("/products/:id", h.DeleteProduct) // 論理削除
+	authed.POST("/products/:id/withdraw", h.WithdrawProduct)
+	authed.POST("/products/:id/relist", h.RelistProduct)
+	authed.POST("/products/:id/purchase", h.PurchaseProduct) // 購入処理（注文の作成）
+
+	// --- 価格交渉 (Offers) ---
+	authed.GET("/products/:id/offers", h.GetProductOffers)
+	authed.POST("/products/:id/offers", h.CreateOffer)
+	authed.GET("/offers/:id", h.GetOffer)
+	authed.POST("/offers/:id/accept", h.AcceptOffer)
+	authed.POST("/offers/:id/reject", h.RejectOffer)
+	authed.POST("/offers/:id/counter", h.CounterOffer)
+	authed.POST("/offers/:id/cancel", h.CancelOffer)
+
+	// --- 画像 (Images) ---
+	authed.POST("/images", h.UploadImage)
+	api.GET("/images/*key", h.ServeImage)
+
+	// --- 注文関連 (Orders) ---
+	authed.GET("/orders", h.GetMyOrders)
+	authed.GET("/orders/:id", h.GetOrderByID)
+	authed.POST("/orders/:id/status", h.UpdateOrderStatus) // 支払い・発送・受取・完了・キャンセル・返金
+	authed.POST("/orders/:id/review", h.SubmitReview)      // 取引完了後の評価
+
+	// --- ユーザー関連 ---
+	api.GET("/users/:uid", h.GetUserByID)
+	api.GET("/users/:uid/profile", h.GetUserProfile)
+	api.GET("/users/:uid/reviews", h.GetUserReviews)
+	authed.POST("/users/sync", h.SyncUser)
+
+	// --- いいね・DM関連 ---
+	authed.PUT("/products/:id/like", h.LikeProduct) // 何度送っても同じ結果（冪等）
+	authed.DELETE("/products/:id/like", h.UnlikeProduct)
+	authed.POST("/likes/toggle", h.ToggleLike) // 互換のために残している。新しい画面は PUT / DELETE を使う
+	api.GET("/likes/status", h.CheckLikeStatus)
+	api.POST("/likes/status", h.GetLikeStatuses) // 一覧の商品をまとめて問い合わせる
+	authed.POST("/messages", h.SendMessage)
+	authed.GET("/messages", h.GetChatHistory)
+	authed.GET("/conversations", h.GetConversations)
+	authed.POST("/conversations/:product_id/:partner_id/read", h.MarkConversationRead)
+	// WebSocket はヘッダーを付けられないので access_token クエリでも認証する
+	api.GET("/messages/ws", auth.QueryToken(verifier, "access_token"), auth.RequireUser(), h.ChatSocket)
+
+	// --- 通知 (Notifications) ---
+	authed.GET("/notifications", h.GetNotifications)
+	authed.POST("/notifications/read", h.MarkNotificationsRead)
+	authed.POST("/notifications/read-all", h.MarkAllNotificationsRead)
+	authed.GET("/notifications/preferences", h.GetNotificationPreferences)
+	authed.PUT("/notifications/preferences", h.UpdateNotificationPreferences)
+	api.GET("/notifications/push/key", h.GetPushKey)
+	authed.POST("/notifications/push/subscriptions", h.SubscribePush)
+	authed.DELETE("/notifications/push/subscriptions", h.UnsubscribePush)
+
+	// --- Gemini AI連携関連 (ここをReactのURLに合わせる) ---
+	// Reactの Sell.tsx が axios.post("/api/ai/description") を叩くので合わせます。
+	// 呼ぶたびに LLM の利用料がかかるのでログイン必須
+	authed.POST("/ai/description", h.GenerateAIDescription)
+	authed.POST("/ai/suggest-price", h.SuggestAIPrice)
+}
+
+// RegisterAdminRoutes は運用向けのルート (/admin と /metrics) を登録する。
+// Firebase のログインとは別に管理トークンで守り、トークンが未設定なら何も登録しない
+func (h *Handler) RegisterAdminRoutes(r gin.IRouter) {
+	if h.Config == nil || h.Config.Server.AdminToken == "" {
+		return
+	}
+	guarded := r.Group("", requestid.Middleware(), apierror.Middleware(h.Logger), auth.RequireAdminToken(h.Config.Server.AdminToken))
+
+	// 実際に使われている設定（パスワードや鍵は伏せ字）
+	guarded.GET("/admin/config", func(c *gin.Context) {
+		c.JSON(200, h.Config.Redacted())
+	})
+	// Prometheus からは Authorization: Bearer に管理トークンを付けて取得する
+	guarded.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
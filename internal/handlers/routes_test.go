@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// publicRoutes は未ログインでも呼べるルート
+var publicRoutes = map[string]bool{
+	"GET /api/health":                 true,
+	"GET /api/products":               true,
+	"GET /api/products/search":        true,
+	"GET /api/products/:id":           true,
+	"GET /api/images/*key":            true,
+	"GET /api/users/:uid":             true,
+	"GET /api/users/:uid/profile":     true,
+	"GET /api/users/:uid/reviews":     true,
+	"GET /api/likes/status":           true,
+	"POST /api/likes/status":          true,
+	"GET /api/notifications/push/key": true,
+}
+
+// TestRoutesRequireLogin は登録された全ルートについて、公開ルート以外が未ログインを401で弾くことを確かめる。
+// ルートを足したら publicRoutes に載せない限りこのテストの対象になる
+func TestRoutesRequireLogin(t *testing.T) {
+	s := newTestServer(t)
+	for _, rt := range s.r.Routes() {
+		key := rt.Method + " " + rt.Path
+		if publicRoutes[key] {
+			continue
+		}
+		t.Run(key, func(t *testing.T) {
+			path := strings.NewReplacer(":id", "1", ":uid", "u1", ":product_id", "1", ":partner_id", "u2", "*key", "x").Replace(rt.Path)
+			s.with(t).expectError(http.StatusUnauthorized, "login_required", rt.Method, path, "", map[string]any{})
+		})
+	}
+}
+
+// TestAIRoutesRequireLogin は LLM の利用料がかかるルートを未ログインでは呼べないことを確かめる
+func TestAIRoutesRequireLogin(t *testing.T) {
+	s := newTestServer(t)
+	for _, path := range []string{"/api/ai/description", "/api/ai/suggest-price"} {
+		s.expectError(http.StatusUnauthorized, "login_required", "POST", path, "", map[string]any{"title": "camera"})
+	}
+	if calls := s.llm.Calls(); len(calls) != 0 {
+		t.Fatalf("LLM called %d times without login", len(calls))
+	}
+}
+
+func TestRoutesRejectInvalidToken(t *testing.T) {
+	s := newTestServer(t)
+	for _, header := range []string{"Bearer not-a-token", "Basic abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		req.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		s.r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", header, w.Code)
+		}
+	}
+}
+
+// TestRoutesNotFound は存在しない対象を指すリクエストが 404 とその種類のコードを返すことを確かめる
+func TestRoutesNotFound(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+
+	tests := []struct {
+		method, path string
+		body         any
+		code         string
+	}{
+		{"GET", "/api/products/999", nil, "product_not_found"},
+		{"GET", "/api/products/abc", nil, "product_not_found"},
+		{"PATCH", "/api/products/999", map[string]any{"title": "x", "version": 1}, "product_not_found"},
+		{"DELETE", "/api/products/999", nil, "product_not_found"},
+		{"POST", "/api/products/999/withdraw", nil, "product_not_found"},
+		{"POST", "/api/products/999/relist", nil, "product_not_found"},
+		{"POST", "/api/products/999/purchase", nil, "product_not_found"},
+		{"PUT", "/api/products/999/like", nil, "product_not_found"},
+		{"DELETE", "/api/products/999/like", nil, "product_not_found"},
+		{"POST", "/api/products/999/offers", map[string]any{"price": 500}, "product_not_found"},
+		{"GET", "/api/offers/999", nil, "offer_not_found"},
+		{"POST", "/api/offers/999/accept", nil, "offer_not_found"},
+		{"POST", "/api/offers/999/reject", nil, "offer_not_found"},
+		{"POST", "/api/offers/999/counter", map[string]any{"price": 500}, "offer_not_found"},
+		{"POST", "/api/offers/999/cancel", nil, "offer_not_found"},
+		{"GET", "/api/orders/999", nil, "order_not_found"},
+		{"POST", "/api/orders/999/status", map[string]any{"status": "paid"}, "order_not_found"},
+		{"POST", "/api/orders/999/review", map[string]any{"rating": "good"}, "order_not_found"},
+		{"GET", "/api/users/nobody", nil, "user_not_found"},
+		{"GET", "/api/users/nobody/profile", nil, "user_not_found"},
+		{"GET", "/api/images/products/nobody/missing.png", nil, "image_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			s.with(t).expectError(http.StatusNotFound, tt.code, tt.method, tt.path, "alice", tt.body)
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t)
+	s.expect(http.StatusOK, "GET", "/api/health", "", nil, nil)
+}
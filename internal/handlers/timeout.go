@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ルートごとの処理時間の上限。超えると c.Request のコンテキストが切れ、DB や AI の呼び出しが中断される
+const (
+	defaultTimeout = 15 * time.Second
+	uploadTimeout  = time.Minute
+	// aiRetryMargin は AI のルートで生成2回分の時間に足す余裕（入力の検証や画像の読み込み、応答の検証）
+	aiRetryMargin = 30 * time.Second
+)
+
+// RouteTimeouts は既定と違う上限を持つルートを返す（キーは gin のルートパス）。0 は上限なし。
+// 価格査定は応答が不正なとき1回やり直すので、AI のルートは1回分 (ai.timeout) の倍に余裕を足す
+func RouteTimeouts(aiTimeout time.Duration) map[string]time.Duration {
+	ai := 2*aiTimeout + aiRetryMargin
+	return map[string]time.Duration{
+		"/api/images":           uploadTimeout,
+		"/api/ai/description":   ai,
+		"/api/ai/suggest-price": ai,
+		"/api/messages/ws":      0, // 接続している間ずっと続く
+	}
+}
+
+// timeout は routes にある上限時間（なければ defaultTimeout）でリクエストのコンテキストを区切る。
+// クライアントが切断したときのキャンセルはもとのコンテキストから引き継ぐ
+func timeout(routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, ok := routes[c.FullPath()]
+		if !ok {
+			d = defaultTimeout
+		}
+		if d > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"backend/internal/services"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	routes := RouteTimeouts(2 * time.Minute)
+	// 価格査定のやり直しを含めて2回分の生成が収まること
+	for _, path := range []string{"/api/ai/description", "/api/ai/suggest-price"} {
+		if got := routes[path]; got <= 4*time.Minute {
+			t.Errorf("%s = %s, want more than twice ai.timeout", path, got)
+		}
+	}
+	if got, ok := routes["/api/messages/ws"]; !ok || got != 0 {
+		t.Errorf("/api/messages/ws = %s, %v; want no limit", got, ok)
+	}
+}
+
+func TestAIRouteTimeout(t *testing.T) {
+	s := newTestServer(t)
+	// 上限を過ぎた生成は呼び出し元のコンテキストのエラーで終わる
+	s.llm.Handler = func(services.Request) (services.Response, error) {
+		time.Sleep(50 * time.Millisecond)
+		return services.Response{}, context.DeadlineExceeded
+	}
+	r := gin.New()
+	s.h.RegisterRoutes(r, testVerifier(t), map[string]time.Duration{"/api/ai/description": 10 * time.Millisecond})
+	s.r = r
+	s.expectError(http.StatusGatewayTimeout, "timeout", "POST", "/api/ai/description", "alice", map[string]any{"title": "camera"})
+}
@@ -1,119 +1,152 @@
 package handlers
 
 import (
-	"backend/internal/db"
+	"backend/internal/apierror"
+	"backend/internal/auth"
 	"backend/internal/models"
+	"backend/internal/repository"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
 )
 
 // 単一ユーザー情報取得API (/api/users/:uid)
-func GetUserByID(c *gin.Context) {
-	userID := c.Param("uid")
-	var user models.User
-	err := db.DB.QueryRow("SELECT id, name, email, avatar_url, created_at FROM users WHERE id = ?", userID).
-		Scan(&user.ID, &user.Name, &user.Email, &user.AvatarURL, &user.CreatedAt)
+func (h *Handler) GetUserByID(c *gin.Context) {
+	user, err := h.Users.Get(c.Request.Context(), c.Param("uid"))
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.UserNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
+	rating, err := h.Reviews.Summary(c.Request.Context(), user.ID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+	user.Rating = &rating
 	c.JSON(http.StatusOK, user)
 }
 
-func GetUserProfile(c *gin.Context) {
-	userID := c.Param("uid") // Firebaseから送られてくるUID
-
-	var profile models.UserProfileResponse
-
-	// 1. ユーザー基本情報
-	db.DB.QueryRow("SELECT id, name, email, avatar_url FROM users WHERE id = ?", userID).
-		Scan(&profile.User.ID, &profile.User.Name, &profile.User.Email, &profile.User.AvatarURL)
-
-	// 2. 出品中の商品（これに紐づくDMもフロントでフィルタリングできるよう商品IDを付与）
-	rows, _ := db.DB.Query("SELECT id, title, price, image_url FROM products WHERE seller_id = ?", userID)
-	for rows.Next() {
-		var p models.Product
-		rows.Scan(&p.ID, &p.Title, &p.Price, &p.ImageURL)
-		// likesテーブルからlike数をカウント
-		var likeCount int
-		db.DB.QueryRow("SELECT COUNT(*) FROM likes WHERE product_id = ?", p.ID).Scan(&likeCount)
-		p.LikeCount = likeCount
-		profile.SellingProducts = append(profile.SellingProducts, p)
+// --- プロフィール ---
+// ?limit=（一覧ごとの件数）&selling_cursor=&liked_cursor=&conversations_cursor=&section=selling|liked|conversations
+// 出品中・いいね・会話の一覧はそれぞれのカーソルで続きを読む。section を指定するとその一覧だけを返す。
+// 会話はメッセージの本文を含むので本人にだけ返す
+func (h *Handler) GetUserProfile(c *gin.Context) {
+	userID := c.Param("uid")
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
 	}
-
-	// 3. いいねした商品
-	likedRows, _ := db.DB.Query(`
-		SELECT p.id, p.title, p.price, p.image_url 
-		FROM products p JOIN likes l ON p.id = l.product_id 
-		WHERE l.user_id = ?`, userID)
-	for likedRows.Next() {
-		var p models.Product
-		likedRows.Scan(&p.ID, &p.Title, &p.Price, &p.ImageURL)
-		profile.LikedProducts = append(profile.LikedProducts, p)
+	section := c.Query("section")
+	switch section {
+	case "", "selling", "liked", "conversations":
+	default:
+		apierror.Abort(c, apierror.InvalidParam.With("section"))
+		return
 	}
+	want := func(s string) bool { return section == "" || section == s }
+	// gin.Context はゴルーチンから触らないので、パラメータは先に読んでおく
+	selling := repository.PageQuery{Limit: limit, Cursor: c.Query("selling_cursor")}
+	liked := repository.PageQuery{Limit: limit, Cursor: c.Query("liked_cursor")}
+	conversations := repository.PageQuery{Limit: limit, Cursor: c.Query("conversations_cursor")}
+	viewer := auth.UID(c)
+	isOwner := viewer == userID
 
-	// 4. DM履歴（自分が関わっている全てのメッセージ）
-	msgRows, _ := db.DB.Query("SELECT product_id, sender_id, receiver_id, content FROM messages WHERE sender_id = ? OR receiver_id = ?", userID, userID)
-	type MessageWithPartner struct {
-		ProductID  int    `json:"product_id"`
-		SenderID   string `json:"sender_id"`
-		ReceiverID string `json:"receiver_id"`
-		Content    string `json:"content"`
-		PartnerID  string `json:"partner_id"`
-	}
-	var latestMessages []MessageWithPartner
-	for msgRows.Next() {
-		var m models.Message
-		var partnerID string
-		msgRows.Scan(&m.ProductID, &m.SenderID, &m.ReceiverID, &m.Content)
-		if m.SenderID == userID {
-			partnerID = m.ReceiverID
-		} else {
-			partnerID = m.SenderID
+	// 各クエリは独立しているので並行に投げる。どれかが失敗したら残りも打ち切る
+	var profile models.UserProfileResponse
+	g, ctx := errgroup.WithContext(c.Request.Context())
+	g.Go(func() error {
+		user, err := h.Users.Get(ctx, userID)
+		if err != nil {
+			return err
+		}
+		rating, err := h.Reviews.Summary(ctx, userID)
+		if err != nil {
+			return err
 		}
-		latestMessages = append(latestMessages, MessageWithPartner{
-			ProductID:  m.ProductID,
-			SenderID:   m.SenderID,
-			ReceiverID: m.ReceiverID,
-			Content:    m.Content,
-			PartnerID:  partnerID,
+		user.Rating = &rating
+		profile.User = *user
+		return nil
+	})
+	if want("selling") {
+		g.Go(func() (err error) {
+			if profile.SellingProducts, err = h.Products.ListBySeller(ctx, userID, selling); err != nil {
+				return cursorParam(err, "selling_cursor")
+			}
+			return h.withLikedByMe(ctx, viewer, profile.SellingProducts.Items)
 		})
 	}
-	// profile.LatestMessagesはinterface{}で上書き
-	type UserProfileResponseWithPartner struct {
-		User            models.User      `json:"user"`
-		SellingProducts []models.Product `json:"selling_products"`
-		LikedProducts   []models.Product `json:"liked_products"`
-		LatestMessages  interface{}      `json:"latest_messages"`
+	if want("liked") {
+		g.Go(func() (err error) {
+			if profile.LikedProducts, err = h.Likes.ListLikedProducts(ctx, userID, liked); err != nil {
+				return cursorParam(err, "liked_cursor")
+			}
+			return h.withLikedByMe(ctx, viewer, profile.LikedProducts.Items)
+		})
 	}
-	resp := UserProfileResponseWithPartner{
-		User:            profile.User,
-		SellingProducts: profile.SellingProducts,
-		LikedProducts:   profile.LikedProducts,
-		LatestMessages:  latestMessages,
+	if want("conversations") && isOwner {
+		g.Go(func() (err error) {
+			profile.Conversations, err = h.Conversations.List(ctx, userID, conversations)
+			return cursorParam(err, "conversations_cursor")
+		})
 	}
-	c.JSON(http.StatusOK, resp)
-	return
 
+	var apiErr *apierror.Error
+	err := g.Wait()
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, apierror.UserNotFound)
+		return
+	case errors.As(err, &apiErr):
+		apierror.Abort(c, apiErr)
+		return
+	case err != nil:
+		apierror.Abort(c, apierror.Internal.Wrap(err))
+		return
+	}
+
+	for _, page := range []*models.ProductPage{profile.SellingProducts, profile.LikedProducts} {
+		if page == nil {
+			continue
+		}
+		for i := range page.Items {
+			h.withImageURL(&page.Items[i])
+		}
+	}
+	if profile.Conversations != nil {
+		h.withConversationImages(profile.Conversations.Items)
+	}
 	c.JSON(http.StatusOK, profile)
 }
 
-func SyncUser(c *gin.Context) {
-	var u models.User
-	if err := c.ShouldBindJSON(&u); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// cursorParam は不正なカーソルのエラーを、どのパラメータが悪いかを示す400にする
+func cursorParam(err error, name string) error {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return apierror.InvalidParam.With(name)
 	}
+	return err
+}
 
-	// ON DUPLICATE KEY UPDATE を使って、存在しなければ作成、あれば更新
-	_, err := db.DB.Exec(
-		"INSERT INTO users (id, name, email, avatar_url) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE name=?, avatar_url=?",
-		u.ID, u.Name, u.Email, u.AvatarURL, u.Name, u.AvatarURL,
-	)
+func (h *Handler) SyncUser(c *gin.Context) {
+	var req SyncUserRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	// IDとメールアドレスは検証済みトークンの値を使う。メールは通知の送り先になるので、
+	// 持ち主であることを Firebase が確認したものだけを保存する（電話番号や匿名のログインでは空）
+	token := auth.CurrentToken(c)
+	u := models.User{ID: token.UID, Name: req.Name, AvatarURL: req.AvatarURL}
+	if token.EmailVerified {
+		u.Email = token.Email
+	}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ユーザー同期に失敗しました"})
+	if err := h.Users.Upsert(c.Request.Context(), &u); err != nil {
+		apierror.Abort(c, apierror.Internal.Wrap(err))
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "user synced"})
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestSyncAndGetUser(t *testing.T) {
+	s := newTestServer(t)
+	s.expect(http.StatusOK, "POST", "/api/users/sync", "alice", map[string]any{"name": "Alice", "email": "other@example.com"}, nil)
+
+	var u models.User
+	s.expect(http.StatusOK, "GET", "/api/users/alice", "", nil, &u)
+	if u.Name != "Alice" || u.Rating == nil {
+		t.Fatalf("user = %+v", u)
+	}
+	s.expectError(http.StatusBadRequest, "validation_failed", "POST", "/api/users/sync", "alice", map[string]any{"avatar_url": "not a url"})
+}
+
+// TestSyncUserEmail はメールの送り先になるアドレスを、確認済みのトークンからしか保存しないことを確かめる
+func TestSyncUserEmail(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		uid    string
+		claims map[string]any
+		want   string
+	}{
+		{"alice", nil, "alice@example.com"},
+		{"bob", map[string]any{"email_verified": false}, ""},
+		{"carol", map[string]any{"email": nil, "email_verified": nil}, ""}, // 電話番号や匿名のログイン
+	}
+	for _, tt := range tests {
+		w := s.doToken("POST", "/api/users/sync", idToken(t, tt.uid, tt.claims), map[string]any{"name": tt.uid, "email": "victim@example.com"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: sync = %d: %s", tt.uid, w.Code, w.Body.String())
+		}
+		u, err := s.h.Users.Get(context.Background(), tt.uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Email != tt.want {
+			t.Errorf("%s: email = %q, want %q", tt.uid, u.Email, tt.want)
+		}
+	}
+
+	// 確認済みになったら次の同期で保存される
+	s.doToken("POST", "/api/users/sync", idToken(t, "bob"), map[string]any{"name": "bob"})
+	if u, _ := s.h.Users.Get(context.Background(), "bob"); u.Email != "bob@example.com" {
+		t.Errorf("bob's email after verification = %q", u.Email)
+	}
+}
+
+func TestUserProfile(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice")
+	s.createUser("bob")
+	for range 3 {
+		s.createProduct("alice", 1000)
+	}
+	liked := s.createProduct("bob", 500)
+	s.expect(http.StatusOK, "PUT", fmt.Sprintf("/api/products/%d/like", liked), "alice", nil, nil)
+	s.expect(http.StatusCreated, "POST", "/api/messages", "bob", map[string]any{"product_id": liked, "receiver_id": "alice", "content": "hi"}, nil)
+
+	var profile models.UserProfileResponse
+	s.expect(http.StatusOK, "GET", "/api/users/alice/profile?limit=2", "alice", nil, &profile)
+	if len(profile.SellingProducts.Items) != 2 || profile.SellingProducts.NextCursor == "" {
+		t.Fatalf("selling = %+v", profile.SellingProducts)
+	}
+	if len(profile.LikedProducts.Items) != 1 || profile.LikedProducts.Items[0].ID != liked {
+		t.Fatalf("liked = %+v", profile.LikedProducts)
+	}
+	if profile.Conversations == nil || len(profile.Conversations.Items) != 1 {
+		t.Fatalf("owner's conversations = %+v", profile.Conversations)
+	}
+
+	// 取り下げ中の商品は、いいねした一覧からも消える
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/products/%d/withdraw", liked), "bob", nil, nil)
+	var hidden models.UserProfileResponse
+	s.expect(http.StatusOK, "GET", "/api/users/alice/profile?section=liked", "", nil, &hidden)
+	if hidden.LikedProducts == nil || len(hidden.LikedProducts.Items) != 0 {
+		t.Fatalf("liked after withdraw = %+v", hidden.LikedProducts)
+	}
+	s.expect(http.StatusOK, "POST", fmt.Sprintf("/api/products/%d/relist", liked), "bob", nil, nil)
+
+	// 続きのページは section で出品中の一覧だけを読む
+	var next models.UserProfileResponse
+	s.expect(http.StatusOK, "GET", "/api/users/alice/profile?limit=2&section=selling&selling_cursor="+profile.SellingProducts.NextCursor, "", nil, &next)
+	if len(next.SellingProducts.Items) != 1 || next.LikedProducts != nil {
+		t.Fatalf("second selling page = %+v", next)
+	}
+
+	// 会話は本人にしか返さない
+	var other models.UserProfileResponse
+	s.expect(http.StatusOK, "GET", "/api/users/alice/profile", "bob", nil, &other)
+	if other.Conversations != nil {
+		t.Fatalf("conversations visible to another user: %+v", other.Conversations)
+	}
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/users/alice/profile?section=bogus", "", nil)
+	s.expectError(http.StatusBadRequest, "invalid_param", "GET", "/api/users/alice/profile?liked_cursor=bogus", "", nil)
+}
@@ -0,0 +1,102 @@
+// Package logging は log/slog のロガーを Cloud Logging の構造化ログの形で組み立てる。
+// リクエストIDとトレースIDはコンテキストから拾って各行に付け、メールアドレスやメッセージ本文は伏せる
+package logging
+
+import (
+	"backend/internal/config"
+	"backend/internal/requestid"
+	"context"
+	"io"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cloud Logging がログとトレースを結び付けるのに使う項目
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const (
+	TraceKey        = "logging.googleapis.com/trace"
+	SpanIDKey       = "logging.googleapis.com/spanId"
+	TraceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// New は cfg の形式とレベルで w に書くロガーを作る。
+// projectID があればトレースを projects/<projectID>/traces/<traceID> の形で書き、Cloud Trace から辿れるようにする
+func New(w io.Writer, cfg config.Log, projectID string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level(cfg.Level), ReplaceAttr: replaceAttr}
+	var h slog.Handler
+	if cfg.Format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: h, projectID: projectID})
+}
+
+// level は debug / info / warn / error を slog.Level にする。読めなければ info
+func level(s string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// contextHandler は ctx のリクエストIDとトレースを各行に足す
+type contextHandler struct {
+	slog.Handler
+	projectID string
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		traceID := sc.TraceID().String()
+		if h.projectID != "" {
+			traceID = "projects/" + h.projectID + "/traces/" + traceID
+		}
+		r.AddAttrs(
+			slog.String(TraceKey, traceID),
+			slog.String(SpanIDKey, sc.SpanID().String()),
+			slog.Bool(TraceSampledKey, sc.IsSampled()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), projectID: h.projectID}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), projectID: h.projectID}
+}
+
+// replaceAttr はレベルと本文を Cloud Logging の項目名 (severity / message) にし、個人情報を伏せる
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.LevelKey:
+			return slog.String("severity", severity(a.Value.Any().(slog.Level)))
+		case slog.MessageKey:
+			a.Key = "message"
+		}
+	}
+	return redact(a)
+}
+
+// severity は Cloud Logging の LogSeverity の名前
+func severity(l slog.Level) string {
+	switch {
+	case l < slog.LevelInfo:
+		return "DEBUG"
+	case l < slog.LevelWarn:
+		return "INFO"
+	case l < slog.LevelError:
+		return "WARNING"
+	default:
+		return "ERROR"
+	}
+}
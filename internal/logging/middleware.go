@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quietRoutes はアクセスログを DEBUG に落とすルート（定期的に叩かれるだけのもの）
+var quietRoutes = map[string]bool{
+	"/api/health": true,
+	"/metrics":    true,
+}
+
+// AccessLog は1リクエストにつき1行、Cloud Logging の httpRequest の形で残す。
+// クエリ文字列（トークンやユーザーIDを含むことがある）と接続元IPは載せない
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		lvl := slog.LevelInfo
+		switch {
+		case quietRoutes[c.FullPath()]:
+			lvl = slog.LevelDebug
+		case status >= http.StatusInternalServerError:
+			lvl = slog.LevelError
+		case status >= http.StatusBadRequest:
+			lvl = slog.LevelWarn
+		}
+		// 上位のミドルウェアやハンドラーが差し替えた後のコンテキスト（リクエストIDやスパン入り）を使う
+		logger.LogAttrs(c.Request.Context(), lvl, "request",
+			slog.String("route", c.FullPath()),
+			slog.Group("httpRequest",
+				slog.String("requestMethod", c.Request.Method),
+				slog.String("requestUrl", c.Request.URL.Path),
+				slog.Int("status", status),
+				slog.Int("responseSize", max(c.Writer.Size(), 0)),
+				slog.String("userAgent", c.Request.UserAgent()),
+				slog.String("protocol", c.Request.Proto),
+				slog.String("latency", fmt.Sprintf("%.6fs", time.Since(start).Seconds())),
+			),
+		)
+	}
+}
+
+// Recovery は panic をスタックトレース付きの ERROR として残し、500 を返す。
+// stack_trace があると Error Reporting がエラーとして拾う
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, rec any) {
+		logger.ErrorContext(c.Request.Context(), "panic recovered",
+			slog.Any("panic", rec),
+			slog.String("stack_trace", string(debug.Stack())),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted は伏せた値の代わりに書く文字列
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys は値をまるごと伏せる項目名（大文字小文字は区別しない）。
+// メッセージ本文や通知の文面はユーザーが書いたものなので、中身を問わず載せない
+var sensitiveKeys = map[string]bool{
+	"email":         true,
+	"content":       true,
+	"text":          true,
+	"body":          true,
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"authorization": true,
+}
+
+// emailPattern はエラーメッセージなどに紛れ込んだメールアドレス
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+
+// redact は項目名で伏せるものを伏せ、残りの文字列とエラーからはメールアドレスを消す。
+// 構造体をそのまま渡すと中身を見られないので、models の型は LogValue で載せる項目を絞っている
+func redact(a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(maskEmails(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(maskEmails(err.Error()))
+		}
+	}
+	return a
+}
+
+func maskEmails(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, Redacted)
+}
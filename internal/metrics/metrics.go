@@ -0,0 +1,143 @@
+// Package metrics は Prometheus 形式のメトリクスを集め、/metrics で公開する
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fleamarket"
+
+// Registry はこのアプリのメトリクスをすべて持つ（Go ランタイムとプロセスの値も含む）
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// --- HTTP ---
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "ルートごとのリクエスト数",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "ルートごとの処理時間",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Middleware はリクエスト数と処理時間をルート（/api/products/:id などのパターン）ごとに数える
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// 存在しないパスをそのまま使うとラベルの種類が際限なく増えるのでまとめる
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// --- DB ---
+
+// RegisterDB は接続プールの状態 (sql.DBStats) を name のラベルで公開する
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// --- AI ---
+
+var (
+	aiRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_requests_total",
+		Help:      "AI の呼び出し回数。result は success / error / malformed（応答がスキーマに合わない）",
+	}, []string{"operation", "result"})
+
+	aiDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "AI の呼び出し1回にかかった時間",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"operation"})
+
+	aiTokens = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_tokens_total",
+		Help:      "AI が使ったトークン数。type は prompt / output",
+	}, []string{"operation", "type"})
+)
+
+// AI の呼び出しの種類
+const (
+	OpDescription = "description"
+	OpPrice       = "price"
+)
+
+// AI の呼び出しの結果
+const (
+	AISuccess   = "success"
+	AIError     = "error"
+	AIMalformed = "malformed"
+)
+
+// ObserveAI は AI の呼び出し1回分を記録する
+func ObserveAI(operation, result string, d time.Duration, promptTokens, outputTokens int) {
+	aiRequests.WithLabelValues(operation, result).Inc()
+	aiDuration.WithLabelValues(operation).Observe(d.Seconds())
+	if promptTokens > 0 {
+		aiTokens.WithLabelValues(operation, "prompt").Add(float64(promptTokens))
+	}
+	if outputTokens > 0 {
+		aiTokens.WithLabelValues(operation, "output").Add(float64(outputTokens))
+	}
+}
+
+// --- 業務 ---
+
+var (
+	ProductsCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "products_created_total",
+		Help:      "出品された商品の数",
+	})
+	Purchases = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "purchases_total",
+		Help:      "購入（注文の作成）の数",
+	})
+	MessagesSent = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "送られたメッセージの数",
+	})
+)
+
+// Handler は Registry の内容を Prometheus のテキスト形式で返す
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Conversation は受信箱の1行。商品 × 相手ごとに1件
+type Conversation struct {
+	Product       ProductSummary `json:"product"`
+	Partner       UserSummary    `json:"partner"`
+	LastMessage   Message        `json:"last_message"`
+	LastMessageAt time.Time      `json:"last_message_at"`
+	UnreadCount   int            `json:"unread_count"` // 相手から届いた未読メッセージの数
+}
+
+type ConversationPage struct {
+	Items      []Conversation `json:"items"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+// UserSummary は一覧に載せるユーザーの最小限の情報。
+// users に行が無い（未同期の）ユーザーは ID だけが入る
+type UserSummary struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// ProductSummary は一覧に載せる商品の最小限の情報
+type ProductSummary struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Price    int    `json:"price"`
+	ImageURL string `json:"image_url"`
+	ImageKey string `json:"image_key"`
+	IsSold   bool   `json:"is_sold"`
+}
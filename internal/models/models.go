@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"log/slog"
+	"time"
+)
 
 type User struct {
 	ID        string    `json:"id"`
@@ -8,6 +11,13 @@ type User struct {
 	Email     string    `json:"email"`
 	AvatarURL string    `json:"avatar_url"`
 	CreatedAt time.Time `json:"created_at"`
+	// Rating は受けた評価の集計。ユーザー情報を返すAPIでだけ埋める
+	Rating *RatingSummary `json:"rating,omitempty"`
+}
+
+// LogValue はログに載せるときの User。メールアドレスと名前は載せない
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", u.ID))
 }
 
 type Product struct {
@@ -17,9 +27,38 @@ type Product struct {
 	Description string    `json:"description"`
 	Price       int       `json:"price"`
 	ImageURL    string    `json:"image_url"`
-	IsSold      bool      `json:"is_sold"` // 追加
+	ImageKey    string    `json:"image_key"` // ストレージ上の画像のキー
+	IsSold      bool      `json:"is_sold"`   // 追加
 	CreatedAt   time.Time `json:"created_at"`
 	LikeCount   int       `json:"like_count"` // 追加: いいね数
+	// LikedByMe はログインしているユーザーがいいねしているか。未ログインなら省く
+	LikedByMe *bool `json:"liked_by_me,omitempty"`
+	// Version は更新のたびに増える。編集時に送り返してもらい、同時編集を検出する
+	Version     int        `json:"version"`
+	IsWithdrawn bool       `json:"is_withdrawn"` // 出品者が取り下げ中
+	DeletedAt   *time.Time `json:"-"`
+}
+
+// ProductPage は商品一覧の1ページ。NextCursor が空なら最後のページ
+type ProductPage struct {
+	Items      []Product `json:"items"`
+	NextCursor string    `json:"next_cursor"`
+}
+
+// SearchHit は検索結果の1件。Highlight の一致箇所は <mark> で囲んである（HTMLエスケープ済み）
+type SearchHit struct {
+	Product
+	Highlight SearchHighlight `json:"highlight"`
+}
+
+type SearchHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"` // 一致箇所の前後だけを抜き出したもの
+}
+
+type SearchPage struct {
+	Items      []SearchHit `json:"items"`
+	NextCursor string      `json:"next_cursor"`
 }
 
 type Message struct {
@@ -31,14 +70,33 @@ type Message struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// LogValue はログに載せるときの Message。本文は載せない
+func (m Message) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", m.ID),
+		slog.Int("product_id", m.ProductID),
+		slog.String("sender_id", m.SenderID),
+		slog.String("receiver_id", m.ReceiverID),
+	)
+}
+
+// LikeStatus は商品1件分のいいねの状態
+type LikeStatus struct {
+	ProductID int  `json:"product_id"`
+	LikeCount int  `json:"like_count"`
+	LikedByMe bool `json:"liked_by_me"`
+}
+
 type Like struct {
 	UserID    string `json:"user_id"`
 	ProductID int    `json:"product_id"`
 }
 
+// UserProfileResponse はプロフィール画面の内容。一覧はそれぞれ別のカーソルで続きを読む。
+// 返さなかった一覧（会話は本人にだけ返す）は省く
 type UserProfileResponse struct {
-	User            User      `json:"user"`
-	SellingProducts []Product `json:"selling_products"`
-	LikedProducts   []Product `json:"liked_products"`
-	LatestMessages  []Message `json:"latest_messages"`
+	User            User              `json:"user"`
+	SellingProducts *ProductPage      `json:"selling_products,omitempty"`
+	LikedProducts   *ProductPage      `json:"liked_products,omitempty"`
+	Conversations   *ConversationPage `json:"conversations,omitempty"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// NotificationKind は通知のきっかけになった出来事
+type NotificationKind string
+
+const (
+	NotifyLike     NotificationKind = "like"     // 出品した商品にいいねされた
+	NotifyMessage  NotificationKind = "message"  // メッセージが届いた
+	NotifyPurchase NotificationKind = "purchase" // 出品した商品が購入された
+)
+
+// NotificationKinds は設定画面などで列挙するための全種類
+var NotificationKinds = []NotificationKind{NotifyLike, NotifyMessage, NotifyPurchase}
+
+// NotificationChannel は通知の届け方
+type NotificationChannel string
+
+const (
+	ChannelInApp   NotificationChannel = "in_app"  // アプリ内の通知一覧
+	ChannelEmail   NotificationChannel = "email"   // メール
+	ChannelWebPush NotificationChannel = "webpush" // ブラウザのプッシュ通知
+)
+
+// Notification はアプリ内の通知一覧の1件。関係のない ID は0
+type Notification struct {
+	ID        int              `json:"id"`
+	UserID    string           `json:"user_id"`
+	Kind      NotificationKind `json:"kind"`
+	ActorID   string           `json:"actor_id"`
+	ProductID int              `json:"product_id,omitempty"`
+	OrderID   int              `json:"order_id,omitempty"`
+	MessageID int              `json:"message_id,omitempty"`
+	Title     string           `json:"title"`
+	Body      string           `json:"body"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type NotificationPage struct {
+	Items       []Notification `json:"items"`
+	UnreadCount int            `json:"unread_count"`
+	NextCursor  string         `json:"next_cursor,omitempty"`
+}
+
+// NotificationPreferences はチャネルごと・種類ごとに通知を受け取るかどうか
+type NotificationPreferences map[NotificationChannel]map[NotificationKind]bool
+
+// DefaultNotificationPreferences は設定を変えていないユーザーの既定値。
+// アプリ内とプッシュはすべて、メールは購入の通知だけを送る
+func DefaultNotificationPreferences() NotificationPreferences {
+	p := NotificationPreferences{}
+	for _, ch := range []NotificationChannel{ChannelInApp, ChannelEmail, ChannelWebPush} {
+		p[ch] = map[NotificationKind]bool{}
+		for _, k := range NotificationKinds {
+			p[ch][k] = ch != ChannelEmail || k == NotifyPurchase
+		}
+	}
+	return p
+}
+
+func (p NotificationPreferences) Enabled(ch NotificationChannel, kind NotificationKind) bool {
+	return p[ch][kind]
+}
+
+// PushSubscription はブラウザの PushManager.subscribe() で得た購読情報
+type PushSubscription struct {
+	UserID    string    `json:"-"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"p256dh"` // ブラウザの公開鍵（base64url）
+	Auth      string    `json:"auth"`   // 認証用シークレット（base64url）
+	CreatedAt time.Time `json:"created_at"`
+}
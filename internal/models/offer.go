@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+type OfferStatus string
+
+const (
+	OfferPending   OfferStatus = "pending"   // 相手の返答待ち
+	OfferAccepted  OfferStatus = "accepted"  // 承諾済み。期限まで買い手のために商品を確保している
+	OfferRejected  OfferStatus = "rejected"  // 断られた
+	OfferCountered OfferStatus = "countered" // 相手が別の価格を提示し返した
+	OfferCancelled OfferStatus = "cancelled" // 提示した本人が取り消した。または購入後に注文がキャンセルされた
+	OfferExpired   OfferStatus = "expired"   // 返答または購入の期限が過ぎた
+	OfferPurchased OfferStatus = "purchased" // 承諾された価格で購入された
+)
+
+// OfferAction は価格提示に対する操作
+type OfferAction string
+
+const (
+	OfferAccept  OfferAction = "accept"
+	OfferReject  OfferAction = "reject"
+	OfferCounter OfferAction = "counter"
+	OfferCancel  OfferAction = "cancel"
+)
+
+// Offer は商品の値下げ交渉での価格提示。買い手が出し、出品者と買い手が交互に
+// counter で提示し返すと、元の提示は countered になり ParentID で前の提示を指す新しい提示ができる。
+// ExpiresAt は pending なら返答の期限、accepted なら承諾価格で購入できる期限
+type Offer struct {
+	ID         int         `json:"id"`
+	ProductID  int         `json:"product_id"`
+	BuyerID    string      `json:"buyer_id"`
+	SellerID   string      `json:"seller_id"`
+	ProposedBy OrderRole   `json:"proposed_by"` // 価格を提示した側
+	Price      int         `json:"price"`
+	Status     OfferStatus `json:"status"`
+	ParentID   *int        `json:"parent_id,omitempty"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// RoleOf は uid が提示の買い手か売り手かを返す。どちらでもなければ空文字
+func (o *Offer) RoleOf(uid string) OrderRole {
+	switch uid {
+	case o.BuyerID:
+		return RoleBuyer
+	case o.SellerID:
+		return RoleSeller
+	}
+	return ""
+}
+
+// Live は期限内で、返答待ちまたは承諾済みなら true
+func (o *Offer) Live(now time.Time) bool {
+	return (o.Status == OfferPending || o.Status == OfferAccepted) && now.Before(o.ExpiresAt)
+}
+
+// Refresh は期限を過ぎた返答待ち・承諾済みの提示を expired として扱う。
+// 期限切れの反映は定期処理で行うので、それまでの間も正しい状態を返すために使う
+func (o *Offer) Refresh(now time.Time) {
+	if (o.Status == OfferPending || o.Status == OfferAccepted) && !now.Before(o.ExpiresAt) {
+		o.Status = OfferExpired
+	}
+}
+
+// Allows は uid が action を行えるかを返す。返答（承諾・拒否・提示し返し）は提示された側、
+// 取り消しは提示した本人が返答待ちの間だけ行える。承諾済みの提示は買い手が取り消して確保を解除できる
+func (o *Offer) Allows(uid string, action OfferAction) bool {
+	role := o.RoleOf(uid)
+	if role == "" {
+		return false
+	}
+	switch action {
+	case OfferAccept, OfferReject, OfferCounter:
+		return o.Status == OfferPending && role != o.ProposedBy
+	case OfferCancel:
+		return (o.Status == OfferPending && role == o.ProposedBy) || (o.Status == OfferAccepted && role == RoleBuyer)
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+type OrderStatus string
+
+const (
+	OrderPendingPayment OrderStatus = "pending_payment"
+	OrderPaid           OrderStatus = "paid"
+	OrderShipped        OrderStatus = "shipped"
+	OrderDelivered      OrderStatus = "delivered"
+	OrderCompleted      OrderStatus = "completed"
+	OrderCancelled      OrderStatus = "cancelled"
+	OrderRefunded       OrderStatus = "refunded"
+)
+
+// OrderRole は注文に対する操作者の立場
+type OrderRole string
+
+const (
+	RoleBuyer  OrderRole = "buyer"
+	RoleSeller OrderRole = "seller"
+)
+
+type Order struct {
+	ID        int         `json:"id"`
+	ProductID int         `json:"product_id"`
+	BuyerID   string      `json:"buyer_id"`
+	SellerID  string      `json:"seller_id"`
+	Price     int         `json:"price"`
+	Status    OrderStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// orderTransitions: 遷移元 -> 遷移先 -> 遷移できる立場
+var orderTransitions = map[OrderStatus]map[OrderStatus][]OrderRole{
+	OrderPendingPayment: {
+		OrderPaid:      {RoleBuyer},
+		OrderCancelled: {RoleBuyer, RoleSeller},
+	},
+	OrderPaid: {
+		OrderShipped:  {RoleSeller},
+		OrderRefunded: {RoleSeller},
+	},
+	OrderShipped: {
+		OrderDelivered: {RoleBuyer},
+		OrderRefunded:  {RoleSeller},
+	},
+	OrderDelivered: {
+		OrderCompleted: {RoleBuyer},
+		OrderRefunded:  {RoleSeller},
+	},
+}
+
+// CanTransition は role の立場で from から to へ進めてよいかを返す
+func CanTransition(from, to OrderStatus, role OrderRole) bool {
+	for _, r := range orderTransitions[from][to] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Relists は注文がこの状態になったとき商品を再び購入できるようにするか。
+// 返金は商品が出品者の手元に戻る前提で、キャンセルと同じく出品中に戻す（売らないなら出品者が取り下げる）
+func (s OrderStatus) Relists() bool {
+	return s == OrderCancelled || s == OrderRefunded
+}
+
+// RoleOf は uid が注文の買い手か売り手かを返す。どちらでもなければ空文字
+func (o *Order) RoleOf(uid string) OrderRole {
+	switch uid {
+	case o.BuyerID:
+		return RoleBuyer
+	case o.SellerID:
+		return RoleSeller
+	}
+	return ""
+}
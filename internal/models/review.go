@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Rating string
+
+const (
+	RatingGood   Rating = "good"
+	RatingNormal Rating = "normal"
+	RatingBad    Rating = "bad"
+)
+
+// Review は取引相手への評価。ReviewerRole は評価した人がその取引で買い手だったか売り手だったか
+type Review struct {
+	ID           int         `json:"id"`
+	OrderID      int         `json:"order_id"`
+	ReviewerID   string      `json:"reviewer_id"`
+	RevieweeID   string      `json:"reviewee_id"`
+	ReviewerRole OrderRole   `json:"reviewer_role"`
+	Rating       Rating      `json:"rating"`
+	Comment      string      `json:"comment"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Reviewer     UserSummary `json:"reviewer"` // 一覧で表示する評価者の名前とアイコン
+}
+
+// RatingSummary はユーザーが受けた評価の件数
+type RatingSummary struct {
+	Good   int `json:"good"`
+	Normal int `json:"normal"`
+	Bad    int `json:"bad"`
+	Total  int `json:"total"`
+}
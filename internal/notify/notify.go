@@ -0,0 +1,223 @@
+// Package notify はイベントバスのイベントを通知にして、ユーザーの設定に従って各チャネルで届ける
+package notify
+
+import (
+	"backend/internal/config"
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// likeInterval は同じ人の同じ商品へのいいねを、もう一度通知するまでの間隔。
+// いいねの付け外しを繰り返して、出品者に通知やメールを送りつけられないようにする
+const likeInterval = 24 * time.Hour
+
+// maxLikeKeys を超えたら、間隔の過ぎた記録を捨てる
+const maxLikeKeys = 1024
+
+type likeKey struct {
+	recipient, actor string
+	product          int
+}
+
+// Channel は通知の届け方の1つ
+type Channel interface {
+	Name() models.NotificationChannel
+	// Send は to に n を届ける。届け先が無い（メールアドレスが未登録など）ときは何もしない
+	Send(ctx context.Context, to *models.User, n *models.Notification) error
+}
+
+// Notifier は events.Bus の購読者として通知を作って配る
+type Notifier struct {
+	repos    repository.Repositories
+	channels []Channel
+	pushKey  string
+	log      *slog.Logger
+	now      func() time.Time
+
+	mu    sync.Mutex
+	liked map[likeKey]time.Time // いいねを最後に通知した時刻
+}
+
+// New は channels の順に通知を届ける Notifier を作る。
+// アプリ内通知を先に置くと、後のチャネルで n.ID を使える。logger が nil なら slog.Default
+func New(repos repository.Repositories, logger *slog.Logger, channels ...Channel) *Notifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	n := &Notifier{repos: repos, channels: channels, log: logger, now: time.Now, liked: map[likeKey]time.Time{}}
+	for _, ch := range channels {
+		if wp, ok := ch.(*WebPush); ok {
+			n.pushKey = wp.PublicKey()
+		}
+	}
+	return n
+}
+
+// NewFromConfig は設定で有効にしたチャネルを使う Notifier を作る。アプリ内通知は常に使う。
+// メールは cfg.SMTP.Addr、Web Push は cfg.WebPush.VAPIDPrivateKey が空なら送らない
+func NewFromConfig(repos repository.Repositories, cfg config.Notify, logger *slog.Logger) (*Notifier, error) {
+	channels := []Channel{NewInApp(repos.Notifications)}
+
+	if cfg.SMTP.Addr != "" {
+		mail, err := NewSMTP(SMTPConfig{
+			Addr:     cfg.SMTP.Addr,
+			From:     cfg.SMTP.From,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		})
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, mail)
+	}
+
+	if cfg.WebPush.VAPIDPrivateKey != "" {
+		push, err := NewWebPush(cfg.WebPush.VAPIDPrivateKey, cfg.WebPush.Subject, repos.PushSubscriptions)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, push)
+	}
+	return New(repos, logger, channels...), nil
+}
+
+// PushPublicKey はブラウザの購読に使う VAPID 公開鍵。Web Push を使わないなら空文字
+func (n *Notifier) PushPublicKey() string {
+	return n.pushKey
+}
+
+// Handle は e を通知にして、受け取る設定になっているチャネルで届ける。失敗はログに残すだけ。
+// ctx は発行元のリクエストのものなので、ログにはそのリクエストIDが付く
+func (n *Notifier) Handle(ctx context.Context, e events.Event) {
+	if e.RecipientID == "" || e.RecipientID == e.ActorID || n.throttled(e) {
+		return
+	}
+	note, err := n.build(ctx, e)
+	if err != nil {
+		n.log.ErrorContext(ctx, "notification build failed", "event", e.Type, "error", err)
+		return
+	}
+	prefs, err := n.repos.Notifications.Preferences(ctx, e.RecipientID)
+	if err != nil {
+		n.log.ErrorContext(ctx, "notification preferences failed", "recipient_id", e.RecipientID, "error", err)
+		return
+	}
+	to, err := n.repos.Users.Get(ctx, e.RecipientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		to = &models.User{ID: e.RecipientID}
+	} else if err != nil {
+		n.log.ErrorContext(ctx, "notification recipient failed", "recipient_id", e.RecipientID, "error", err)
+		return
+	}
+
+	for _, ch := range n.channels {
+		if !prefs.Enabled(ch.Name(), note.Kind) {
+			continue
+		}
+		if err := ch.Send(ctx, to, note); err != nil {
+			n.log.WarnContext(ctx, "notification send failed", "channel", ch.Name(), "kind", note.Kind, "recipient_id", e.RecipientID, "error", err)
+		}
+	}
+}
+
+// throttled は同じ人の同じ商品へのいいねの通知を、likeInterval のあいだ1回に抑える。
+// 記録はこのプロセスの中だけに持つ。アプリ内通知を切っているユーザーにもメールが重ならないように、
+// 保存した通知ではなくここで数える
+func (n *Notifier) throttled(e events.Event) bool {
+	if e.Type != events.ProductLiked {
+		return false
+	}
+	key := likeKey{recipient: e.RecipientID, actor: e.ActorID, product: e.ProductID}
+	now := n.now()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if last, ok := n.liked[key]; ok && now.Sub(last) < likeInterval {
+		return true
+	}
+	if len(n.liked) >= maxLikeKeys {
+		for k, last := range n.liked {
+			if now.Sub(last) >= likeInterval {
+				delete(n.liked, k)
+			}
+		}
+	}
+	n.liked[key] = now
+	return false
+}
+
+// build は通知の文面を作る
+func (n *Notifier) build(ctx context.Context, e events.Event) (*models.Notification, error) {
+	note := &models.Notification{
+		UserID:    e.RecipientID,
+		Kind:      models.NotificationKind(e.Type),
+		ActorID:   e.ActorID,
+		ProductID: e.ProductID,
+		OrderID:   e.OrderID,
+		MessageID: e.MessageID,
+	}
+	actor, err := n.actorName(ctx, e.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	product, err := n.repos.Products.Get(ctx, e.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Type {
+	case events.ProductLiked:
+		note.Title = "いいねされました"
+		note.Body = fmt.Sprintf("%sさんが「%s」にいいねしました", actor, product.Title)
+	case events.MessageSent:
+		note.Title = fmt.Sprintf("%sさんからメッセージが届きました", actor)
+		note.Body = fmt.Sprintf("「%s」について: %s", product.Title, truncate(e.Text, 100))
+	case events.ProductPurchased:
+		note.Title = "商品が購入されました"
+		note.Body = fmt.Sprintf("%sさんが「%s」を%d円で購入しました", actor, product.Title, e.Price)
+	default:
+		return nil, fmt.Errorf("unknown event type %q", e.Type)
+	}
+	return note, nil
+}
+
+func (n *Notifier) actorName(ctx context.Context, uid string) (string, error) {
+	u, err := n.repos.Users.Get(ctx, uid)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && u.Name == "") {
+		return "ゲスト", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.Name, nil
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// InApp は通知を通知一覧に保存する
+type InApp struct {
+	repo repository.NotificationRepository
+}
+
+func NewInApp(repo repository.NotificationRepository) *InApp {
+	return &InApp{repo: repo}
+}
+
+func (c *InApp) Name() models.NotificationChannel { return models.ChannelInApp }
+
+func (c *InApp) Send(ctx context.Context, to *models.User, n *models.Notification) error {
+	return c.repo.Create(ctx, n)
+}
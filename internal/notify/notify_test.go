@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/repository/memory"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// newTestNotifier はアプリ内通知と srv へのメールで届ける Notifier を作る。
+// bob の出品した商品を1つ用意して、その id を返す
+func newTestNotifier(t *testing.T, srv *smtpServer) (*Notifier, repository.Repositories, int) {
+	t.Helper()
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	for _, u := range []models.User{
+		{ID: "alice", Name: "アリス", Email: "alice@example.com"},
+		{ID: "bob", Name: "ボブ", Email: "bob@example.com"},
+	} {
+		if err := repos.Users.Upsert(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &models.Product{SellerID: "bob", Title: "ヴィンテージカメラ", Price: 5000}
+	if err := repos.Products.Create(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	mail, err := NewSMTP(SMTPConfig{Addr: srv.addr, From: "noreply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(repos, logger, NewInApp(repos.Notifications), mail), repos, p.ID
+}
+
+// inAppCount は uid のアプリ内通知の件数
+func inAppCount(t *testing.T, repos repository.Repositories, uid string) int {
+	t.Helper()
+	page, err := repos.Notifications.List(context.Background(), uid, repository.NotificationQuery{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(page.Items)
+}
+
+func TestNotifierSendsPurchaseByEmail(t *testing.T) {
+	srv := newSMTPServer(t)
+	n, repos, productID := newTestNotifier(t, srv)
+
+	n.Handle(context.Background(), events.Event{Type: events.ProductPurchased, ActorID: "alice", RecipientID: "bob", ProductID: productID, Price: 4500})
+
+	m := srv.wait(t)
+	if len(m.rcpt) != 1 || m.rcpt[0] != "bob@example.com" {
+		t.Fatalf("rcpt = %v", m.rcpt)
+	}
+	_, subject, body := parseMail(t, m.data)
+	if subject != "商品が購入されました" || body != "アリスさんが「ヴィンテージカメラ」を4500円で購入しました" {
+		t.Fatalf("mail = %q / %q", subject, body)
+	}
+	if got := inAppCount(t, repos, "bob"); got != 1 {
+		t.Fatalf("in-app notifications = %d, want 1", got)
+	}
+}
+
+func TestNotifierFollowsPreferences(t *testing.T) {
+	srv := newSMTPServer(t)
+	n, repos, productID := newTestNotifier(t, srv)
+	ctx := context.Background()
+	like := events.Event{Type: events.ProductLiked, ActorID: "alice", RecipientID: "bob", ProductID: productID}
+	purchase := events.Event{Type: events.ProductPurchased, ActorID: "alice", RecipientID: "bob", ProductID: productID, Price: 5000}
+
+	// 既定ではいいねはメールで送らない（Handle は同期なので、この時点で届いていなければ送られていない）
+	n.Handle(ctx, like)
+	srv.expectNone(t)
+	if got := inAppCount(t, repos, "bob"); got != 1 {
+		t.Fatalf("in-app after like = %d, want 1", got)
+	}
+
+	// 購入のメールとアプリ内通知を止める
+	off := models.NotificationPreferences{
+		models.ChannelEmail: {models.NotifyPurchase: false},
+		models.ChannelInApp: {models.NotifyPurchase: false},
+	}
+	if err := repos.Notifications.SetPreferences(ctx, "bob", off); err != nil {
+		t.Fatal(err)
+	}
+	n.Handle(ctx, purchase)
+	srv.expectNone(t)
+	if got := inAppCount(t, repos, "bob"); got != 1 {
+		t.Fatalf("in-app after disabled purchase = %d, want 1", got)
+	}
+
+	// いいねのメールを有効にすると届く。同じいいねは間隔をあけないと通知しないので時計を進める
+	on := models.NotificationPreferences{models.ChannelEmail: {models.NotifyLike: true}}
+	if err := repos.Notifications.SetPreferences(ctx, "bob", on); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(likeInterval)
+	n.now = func() time.Time { return later }
+	n.Handle(ctx, like)
+	if _, subject, _ := parseMail(t, srv.wait(t).data); subject != "いいねされました" {
+		t.Fatalf("subject = %q", subject)
+	}
+}
+
+func TestNotifierSkipsSelf(t *testing.T) {
+	srv := newSMTPServer(t)
+	n, repos, productID := newTestNotifier(t, srv)
+
+	n.Handle(context.Background(), events.Event{Type: events.ProductLiked, ActorID: "bob", RecipientID: "bob", ProductID: productID})
+	srv.expectNone(t)
+	if got := inAppCount(t, repos, "bob"); got != 0 {
+		t.Fatalf("in-app for own action = %d, want 0", got)
+	}
+}
+
+func TestNotifierThrottlesLikes(t *testing.T) {
+	srv := newSMTPServer(t)
+	n, repos, productID := newTestNotifier(t, srv)
+	ctx := context.Background()
+	on := models.NotificationPreferences{models.ChannelEmail: {models.NotifyLike: true}}
+	if err := repos.Notifications.SetPreferences(ctx, "bob", on); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	n.now = func() time.Time { return now }
+	like := events.Event{Type: events.ProductLiked, ActorID: "alice", RecipientID: "bob", ProductID: productID}
+
+	// いいねを付け直しても、間隔内なら通知もメールも1回だけ
+	n.Handle(ctx, like)
+	srv.wait(t)
+	n.Handle(ctx, like)
+	srv.expectNone(t)
+	if got := inAppCount(t, repos, "bob"); got != 1 {
+		t.Fatalf("in-app after liking twice = %d, want 1", got)
+	}
+
+	// 購入など、いいね以外の通知は抑えない
+	n.Handle(ctx, events.Event{Type: events.ProductPurchased, ActorID: "alice", RecipientID: "bob", ProductID: productID, Price: 5000})
+	srv.wait(t)
+
+	// 間隔が過ぎればまた届く
+	now = now.Add(likeInterval)
+	n.Handle(ctx, like)
+	srv.wait(t)
+	if got := inAppCount(t, repos, "bob"); got != 3 {
+		t.Fatalf("in-app after interval = %d, want 3", got)
+	}
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"backend/internal/models"
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receivedMail は smtpServer が受け取った1通
+type receivedMail struct {
+	from string
+	rcpt []string
+	data string
+}
+
+// smtpServer はテスト用の最小限の SMTP サーバー（STARTTLS と認証には対応しない）。
+// 受け取ったメールは mails に流す
+type smtpServer struct {
+	addr  string
+	mails chan receivedMail
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpServer{addr: ln.Addr().String(), mails: make(chan receivedMail, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP test")
+	var m receivedMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			m.from = addrParam(cmd)
+			reply("250 OK")
+		case "RCPT":
+			m.rcpt = append(m.rcpt, addrParam(cmd))
+			reply("250 OK")
+		case "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			m.data = data.String()
+			s.mails <- m
+			m = receivedMail{}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// addrParam は "MAIL FROM:<a@example.com>" の <> の中を返す
+func addrParam(cmd string) string {
+	_, rest, _ := strings.Cut(cmd, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+// wait は1通受け取るまで待つ
+func (s *smtpServer) wait(t *testing.T) receivedMail {
+	t.Helper()
+	select {
+	case m := <-s.mails:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+		return receivedMail{}
+	}
+}
+
+// expectNone はメールが届いていないことを確かめる
+func (s *smtpServer) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case m := <-s.mails:
+		t.Fatalf("unexpected mail to %v", m.rcpt)
+	default:
+	}
+}
+
+// parseMail は件名と quoted-printable の本文をデコードする
+func parseMail(t *testing.T, data string) (*mail.Message, string, string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg, subject, strings.TrimRight(string(body), "\r\n")
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newSMTPServer(t)
+	ch, err := NewSMTP(SMTPConfig{Addr: srv.addr, From: "フリマ <noreply@example.com>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := "aliceさんが「ヴィンテージカメラ」を5000円で購入しました。長い本文も quoted-printable で折り返される" + strings.Repeat("。", 40)
+	n := &models.Notification{Title: "商品が購入されました", Body: body}
+	if err := ch.Send(context.Background(), &models.User{ID: "bob", Email: "bob@example.com"}, n); err != nil {
+		t.Fatal(err)
+	}
+
+	m := srv.wait(t)
+	if m.from != "noreply@example.com" || len(m.rcpt) != 1 || m.rcpt[0] != "bob@example.com" {
+		t.Fatalf("envelope = %s -> %v", m.from, m.rcpt)
+	}
+	msg, subject, gotBody := parseMail(t, m.data)
+	if to := msg.Header.Get("To"); to != "<bob@example.com>" {
+		t.Errorf("To = %q", to)
+	}
+	if subject != n.Title {
+		t.Errorf("Subject = %q, want %q", subject, n.Title)
+	}
+	if gotBody != body {
+		t.Errorf("body = %q, want %q", gotBody, body)
+	}
+}
+
+func TestSMTPSkipsUserWithoutEmail(t *testing.T) {
+	srv := newSMTPServer(t)
+	ch, err := NewSMTP(SMTPConfig{Addr: srv.addr, From: "noreply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(context.Background(), &models.User{ID: "bob"}, &models.Notification{Title: "x", Body: "y"}); err != nil {
+		t.Fatal(err)
+	}
+	srv.expectNone(t)
+}
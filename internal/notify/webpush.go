@@ -0,0 +1,288 @@
+package notify
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	pushTTL        = 24 * time.Hour // プッシュサービスが端末に届けるまで保持する時間
+	pushRecordSize = 4096
+)
+
+// pushServiceHosts はブラウザのプッシュサービスのホスト。. で始まるものはそのサブドメインを表す。
+// 購読の endpoint には送信先として任意の URL を指定できてしまうので、これらに限る
+var pushServiceHosts = []string{
+	"fcm.googleapis.com",                // Chrome, Edge (Chromium)
+	"updates.push.services.mozilla.com", // Firefox
+	".push.apple.com",                   // Safari
+	".notify.windows.com",               // 旧 Edge (WNS)
+}
+
+// IsPushEndpoint は endpoint が既知のプッシュサービスの https URL かどうかを返す
+func IsPushEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil || (u.Port() != "" && u.Port() != "443") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range pushServiceHosts {
+		if host == h || (strings.HasPrefix(h, ".") && strings.HasSuffix(host, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// errSubscriptionGone はブラウザ側で購読が解除されていたことを表す
+var errSubscriptionGone = errors.New("push subscription expired")
+
+// WebPush はブラウザのプッシュ通知を送る（RFC 8030 / 8291 / 8292）。
+// VAPID の鍵は `npx web-push generate-vapid-keys` などで作った base64url の秘密鍵を使う
+type WebPush struct {
+	subs      repository.PushSubscriptionRepository
+	key       *ecdsa.PrivateKey
+	publicKey string // base64url の非圧縮公開鍵
+	subject   string
+	client    *http.Client
+}
+
+// NewWebPush は base64url の VAPID 秘密鍵（32バイト）から送信側を作る。
+// subject はプッシュサービスからの連絡先（mailto: または https:）
+func NewWebPush(privateKey, subject string, subs repository.PushSubscriptionRepository) (*WebPush, error) {
+	d, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID_PRIVATE_KEY: %w", err)
+	}
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID_PRIVATE_KEY: %w", err)
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, fmt.Errorf("VAPID_SUBJECT must start with mailto: or https:")
+	}
+
+	// JWT の署名には crypto/ecdsa を使うので、同じ鍵を ecdsa の形にする
+	pub := priv.PublicKey().Bytes() // 0x04 || X || Y
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &WebPush{
+		subs:      subs,
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(pub),
+		subject:   subject,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (w *WebPush) Name() models.NotificationChannel { return models.ChannelWebPush }
+
+// PublicKey はフロントエンドの applicationServerKey に渡す公開鍵
+func (w *WebPush) PublicKey() string { return w.publicKey }
+
+// pushPayload は Service Worker の push イベントで受け取る JSON
+type pushPayload struct {
+	ID        int                     `json:"id,omitempty"`
+	Kind      models.NotificationKind `json:"kind"`
+	Title     string                  `json:"title"`
+	Body      string                  `json:"body"`
+	ProductID int                     `json:"product_id,omitempty"`
+	OrderID   int                     `json:"order_id,omitempty"`
+}
+
+// Send はユーザーが購読しているすべてのブラウザに送る。解除済みの購読は削除する
+func (w *WebPush) Send(ctx context.Context, to *models.User, n *models.Notification) error {
+	subs, err := w.subs.ListByUser(ctx, to.ID)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	payload, err := json.Marshal(pushPayload{ID: n.ID, Kind: n.Kind, Title: n.Title, Body: n.Body, ProductID: n.ProductID, OrderID: n.OrderID})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		err := w.push(ctx, sub, payload)
+		if errors.Is(err, errSubscriptionGone) {
+			err = w.subs.Delete(ctx, sub.UserID, sub.Endpoint)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *WebPush) push(ctx context.Context, sub models.PushSubscription, payload []byte) error {
+	// 登録時に確かめているが、それ以前に保存された購読にも送らない
+	if !IsPushEndpoint(sub.Endpoint) {
+		return fmt.Errorf("push endpoint is not a known push service: %s", sub.Endpoint)
+	}
+	body, err := encryptPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+	auth, err := w.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errSubscriptionGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("push service returned %s", resp.Status)
+	}
+	return nil
+}
+
+// vapidAuthorization はプッシュサービスに送信元を示す VAPID ヘッダーを作る（RFC 8292）
+func (w *WebPush) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": w.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, w.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+	return "vapid t=" + token + ", k=" + w.publicKey, nil
+}
+
+// encryptPayload は購読の鍵で本文を暗号化する（RFC 8291 の aes128gcm、1レコード）
+func encryptPayload(sub models.PushSubscription, plaintext []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+	authSecret, err := decodeBase64URL(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+
+	// 送信ごとに使い捨ての鍵とソルトを作る
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+	sharedSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 最後のレコードは区切りの 0x02 を付けて暗号化する
+	record := append(append([]byte{}, plaintext...), 0x02)
+	if len(record)+gcm.Overhead() > pushRecordSize {
+		return nil, fmt.Errorf("push payload too large (%d bytes)", len(plaintext))
+	}
+
+	// ヘッダー: salt(16) || レコードサイズ(4) || 鍵の長さ(1) || 送信側の公開鍵
+	out := make([]byte, 0, 16+4+1+len(asPublic)+len(record)+gcm.Overhead())
+	out = append(out, salt...)
+	out = binary.BigEndian.AppendUint32(out, pushRecordSize)
+	out = append(out, byte(len(asPublic)))
+	out = append(out, asPublic...)
+	return gcm.Seal(out, nonce, record, nil), nil
+}
+
+// decodeBase64URL はパディングの有無どちらの base64url も受け付ける
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
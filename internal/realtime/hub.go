@@ -0,0 +1,170 @@
+package realtime
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const (
+	// EventMessage は新しいチャットメッセージの通知
+	EventMessage = "message"
+	// EventOffer は価格提示の作成・状態変化の通知
+	EventOffer = "offer"
+)
+
+// Event は WebSocket でクライアントに送るイベント
+type Event struct {
+	Type    string          `json:"type"`
+	Message *models.Message `json:"message,omitempty"`
+	Offer   *models.Offer   `json:"offer,omitempty"`
+}
+
+// Thread は商品ごとの2人の会話。A と B は順不同で同じスレッドを指す
+type Thread struct {
+	ProductID int
+	A, B      string
+}
+
+func (t Thread) topic() string {
+	a, b := t.A, t.B
+	if a > b {
+		a, b = b, a
+	}
+	return fmt.Sprintf("thread:%d:%s:%s", t.ProductID, a, b)
+}
+
+// ThreadOf はメッセージが属するスレッドを返す
+func ThreadOf(m *models.Message) Thread {
+	return Thread{ProductID: m.ProductID, A: m.SenderID, B: m.ReceiverID}
+}
+
+// Hub はスレッド単位でイベントを配信する
+type Hub struct {
+	ps PubSub
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub(ps PubSub) *Hub {
+	return &Hub{ps: ps, subs: make(map[*Subscription]struct{})}
+}
+
+// Close はすべての購読を打ち切り、以後の購読もすぐに閉じる。
+// サーバーの停止時に呼ぶと、クライアントは別のインスタンスに再接続する
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := make([]*Subscription, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+func (h *Hub) forget(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, s)
+}
+
+// PublishMessage は保存済みのメッセージをスレッドの購読者に配信する
+func (h *Hub) PublishMessage(ctx context.Context, m models.Message) error {
+	return h.publish(ctx, ThreadOf(&m), Event{Type: EventMessage, Message: &m})
+}
+
+// PublishOffer は価格提示を買い手と出品者の会話スレッドに配信する
+func (h *Hub) PublishOffer(ctx context.Context, o models.Offer) error {
+	return h.publish(ctx, Thread{ProductID: o.ProductID, A: o.BuyerID, B: o.SellerID}, Event{Type: EventOffer, Offer: &o})
+}
+
+func (h *Hub) publish(ctx context.Context, t Thread, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return h.ps.Publish(ctx, t.topic(), payload)
+}
+
+// Subscription はスレッドの購読。受信側が詰まってバッファがあふれたら
+// 購読を打ち切って Events を閉じる（クライアントは再接続して取りこぼしを取り直す）
+type Subscription struct {
+	Events <-chan Event
+
+	hub         *Hub
+	events      chan Event
+	mu          sync.Mutex
+	closed      bool
+	unsubscribe func()
+}
+
+// Subscribe はスレッドを購読する。buffer は受信側が追いつけない間に溜めておける件数
+func (h *Hub) Subscribe(t Thread, buffer int) *Subscription {
+	s := &Subscription{hub: h, events: make(chan Event, buffer)}
+	s.Events = s.events
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		s.Close()
+		return s
+	}
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := h.ps.Subscribe(t.topic(), s.deliver)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		// 登録直後にあふれて閉じられていた
+		go unsubscribe()
+	}
+	s.unsubscribe = unsubscribe
+	return s
+}
+
+func (s *Subscription) deliver(payload []byte) {
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		s.closeLocked()
+	}
+}
+
+// Close は購読をやめる。何度呼んでもよい
+func (s *Subscription) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Subscription) closeLocked() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.events)
+	s.hub.forget(s)
+	// deliver は PubSub のロック中に呼ばれるので、解除は別ゴルーチンで行う
+	if s.unsubscribe != nil {
+		go s.unsubscribe()
+	}
+}
@@ -0,0 +1,60 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// PubSub はトピック単位でメッセージを配信する仕組み。
+// 今はプロセス内の LocalPubSub だけだが、Cloud Run で複数インスタンスに
+// なったら Redis や Cloud Pub/Sub の実装に差し替える
+type PubSub interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe は topic に届いた payload ごとに fn を呼ぶ。返り値の関数で購読をやめる。
+	// fn はブロックしないこと
+	Subscribe(topic string, fn func(payload []byte)) (unsubscribe func())
+}
+
+// LocalPubSub は同じプロセス内だけで配信する PubSub
+type LocalPubSub struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[string]map[int]func([]byte)
+}
+
+func NewLocalPubSub() *LocalPubSub {
+	return &LocalPubSub{subs: make(map[string]map[int]func([]byte))}
+}
+
+func (p *LocalPubSub) Publish(ctx context.Context, topic string, payload []byte) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, fn := range p.subs[topic] {
+		fn(payload)
+	}
+	return nil
+}
+
+func (p *LocalPubSub) Subscribe(topic string, fn func([]byte)) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+	if p.subs[topic] == nil {
+		p.subs[topic] = make(map[int]func([]byte))
+	}
+	p.subs[topic][id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.subs[topic], id)
+			if len(p.subs[topic]) == 0 {
+				delete(p.subs, topic)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor はキーセットページングの位置。クライアントには不透明な文字列として渡す。
+// Value は並び順のキー（価格やいいね数など）、ID は同じ値が並んだときのタイブレーク
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Value float64 `json:"v,omitempty"`
+	ID    int     `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor は文字列をカーソルに戻す。sort が違う並び順のカーソルは受け付けない
+func DecodeCursor(s, sort string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
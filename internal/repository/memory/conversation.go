@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"sort"
+)
+
+type ConversationRepository struct {
+	s *Store
+}
+
+func (r *ConversationRepository) List(ctx context.Context, userID string, q repository.PageQuery) (*models.ConversationPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorConversations)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	type threadKey struct {
+		productID int
+		partnerID string
+	}
+	byThread := map[threadKey]*models.Conversation{}
+	for _, m := range r.s.messages {
+		var k threadKey
+		switch userID {
+		case m.SenderID:
+			k = threadKey{m.ProductID, m.ReceiverID}
+		case m.ReceiverID:
+			k = threadKey{m.ProductID, m.SenderID}
+		default:
+			continue
+		}
+		c := byThread[k]
+		if c == nil {
+			c = &models.Conversation{Partner: models.UserSummary{ID: k.partnerID}, Product: models.ProductSummary{ID: k.productID}}
+			if u, ok := r.s.users[k.partnerID]; ok {
+				c.Partner.Name, c.Partner.AvatarURL = u.Name, u.AvatarURL
+			}
+			if p := r.s.product(k.productID); p != nil {
+				c.Product.Title, c.Product.Price, c.Product.ImageKey, c.Product.IsSold = p.Title, p.Price, p.ImageKey, p.IsSold
+			}
+			byThread[k] = c
+		}
+		// messages は ID 順なので後から来たものが最新
+		c.LastMessage = m
+		c.LastMessageAt = m.CreatedAt
+		if m.SenderID == k.partnerID && m.ID > r.s.reads[readKey{userID, k.productID, k.partnerID}] {
+			c.UnreadCount++
+		}
+	}
+
+	conversations := make([]models.Conversation, 0, len(byThread))
+	for _, c := range byThread {
+		if cursor == nil || c.LastMessage.ID < cursor.ID {
+			conversations = append(conversations, *c)
+		}
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].LastMessage.ID > conversations[j].LastMessage.ID
+	})
+
+	page := &models.ConversationPage{Items: conversations}
+	if q.Limit > 0 && len(conversations) > q.Limit {
+		page.Items = conversations[:q.Limit]
+		page.NextCursor = repository.Cursor{Sort: repository.CursorConversations, ID: page.Items[q.Limit-1].LastMessage.ID}.Encode()
+	}
+	return page, nil
+}
+
+func (r *ConversationRepository) MarkRead(ctx context.Context, userID string, productID int, partnerID string, upToID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if upToID == 0 {
+		for _, m := range r.s.messages {
+			if m.ProductID == productID && m.SenderID == partnerID && m.ReceiverID == userID {
+				upToID = m.ID
+			}
+		}
+	}
+	k := readKey{userID, productID, partnerID}
+	if upToID > r.s.reads[k] {
+		r.s.reads[k] = upToID
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"sort"
+)
+
+type LikeRepository struct {
+	s *Store
+}
+
+func (r *LikeRepository) Toggle(ctx context.Context, userID string, productID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, err := r.s.likable(productID)
+	if err != nil {
+		return false, err
+	}
+	key := likeKey{userID, productID}
+	_, liked := r.s.likes[key]
+	if liked {
+		delete(r.s.likes, key)
+		p.LikeCount--
+	} else {
+		r.s.likes[key] = r.s.now()
+		p.LikeCount++
+	}
+	return !liked, nil
+}
+
+func (r *LikeRepository) Like(ctx context.Context, userID string, productID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, err := r.s.likable(productID)
+	if err != nil {
+		return false, err
+	}
+	key := likeKey{userID, productID}
+	if _, ok := r.s.likes[key]; ok {
+		return false, nil
+	}
+	r.s.likes[key] = r.s.now()
+	p.LikeCount++
+	return true, nil
+}
+
+func (r *LikeRepository) Unlike(ctx context.Context, userID string, productID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, err := r.s.likable(productID)
+	if err != nil {
+		return false, err
+	}
+	key := likeKey{userID, productID}
+	if _, ok := r.s.likes[key]; !ok {
+		return false, nil
+	}
+	delete(r.s.likes, key)
+	p.LikeCount--
+	return true, nil
+}
+
+func (r *LikeRepository) Exists(ctx context.Context, userID string, productID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	_, ok := r.s.likes[likeKey{userID, productID}]
+	return ok, nil
+}
+
+func (r *LikeRepository) Statuses(ctx context.Context, userID string, productIDs []int) (map[int]models.LikeStatus, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	statuses := make(map[int]models.LikeStatus, len(productIDs))
+	for _, id := range productIDs {
+		p := r.s.product(id)
+		if p == nil || p.DeletedAt != nil {
+			continue
+		}
+		_, liked := r.s.likes[likeKey{userID, id}]
+		statuses[id] = models.LikeStatus{ProductID: id, LikeCount: p.LikeCount, LikedByMe: liked}
+	}
+	return statuses, nil
+}
+
+func (r *LikeRepository) ListLikedProducts(ctx context.Context, userID string, q repository.PageQuery) (*models.ProductPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorLiked)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// MySQL の DATETIME に合わせて、いいねした日時は秒単位で比べる
+	type liked struct {
+		p  models.Product
+		at int64
+	}
+	var all []liked
+	for _, p := range r.s.products {
+		at, ok := r.s.likes[likeKey{userID, p.ID}]
+		if !ok || p.DeletedAt != nil || p.IsWithdrawn {
+			continue
+		}
+		p.Description = ""
+		all = append(all, liked{p, at.Unix()})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].at != all[j].at {
+			return all[i].at > all[j].at
+		}
+		return all[i].p.ID > all[j].p.ID
+	})
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	for i, l := range all {
+		if cursor != nil && (l.at > int64(cursor.Value) || (l.at == int64(cursor.Value) && l.p.ID >= cursor.ID)) {
+			continue
+		}
+		if len(page.Items) == q.Limit {
+			prev := all[i-1]
+			page.NextCursor = repository.Cursor{Sort: repository.CursorLiked, Value: float64(prev.at), ID: prev.p.ID}.Encode()
+			break
+		}
+		page.Items = append(page.Items, l.p)
+	}
+	return page, nil
+}
@@ -0,0 +1,103 @@
+// Package memory は repository のインターフェースをメモリ上で実装する。
+// DBなしでハンドラーをテストするためのもので、全リポジトリが1つの Store を共有する
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"sync"
+	"time"
+)
+
+type likeKey struct {
+	userID    string
+	productID int
+}
+
+type readKey struct {
+	userID    string
+	productID int
+	partnerID string
+}
+
+// Store はすべてのテーブルをまとめて持つ。ロックは1つなのでトランザクションも単純に書ける
+type Store struct {
+	mu sync.Mutex
+	// now は作成日時に使う。テストで固定したい場合は差し替える
+	now func() time.Time
+
+	users    map[string]models.User
+	products []models.Product
+	likes    map[likeKey]time.Time
+	messages []models.Message
+	reads    map[readKey]int // 既読にした最後のメッセージID
+	orders   []models.Order
+	reviews  []models.Review
+	offers   []models.Offer
+
+	notifications []models.Notification
+	prefs         map[string]models.NotificationPreferences // 既定値から変えた設定だけ
+	pushSubs      map[string]models.PushSubscription        // endpoint ごと
+}
+
+func NewStore() *Store {
+	return &Store{
+		now:      time.Now,
+		users:    map[string]models.User{},
+		likes:    map[likeKey]time.Time{},
+		reads:    map[readKey]int{},
+		prefs:    map[string]models.NotificationPreferences{},
+		pushSubs: map[string]models.PushSubscription{},
+	}
+}
+
+// NewRepositories は新しい空の Store を使うリポジトリ一式を作る
+func NewRepositories() repository.Repositories {
+	return NewStore().Repositories()
+}
+
+func (s *Store) Repositories() repository.Repositories {
+	return repository.Repositories{
+		Products:          &ProductRepository{s},
+		Users:             &UserRepository{s},
+		Likes:             &LikeRepository{s},
+		Messages:          &MessageRepository{s},
+		Conversations:     &ConversationRepository{s},
+		Orders:            &OrderRepository{s},
+		Reviews:           &ReviewRepository{s},
+		Offers:            &OfferRepository{s},
+		Notifications:     &NotificationRepository{s},
+		PushSubscriptions: &PushSubscriptionRepository{s},
+	}
+}
+
+// product は id の商品のポインタを返す。呼び出し側で mu を持っていること
+func (s *Store) product(id int) *models.Product {
+	for i := range s.products {
+		if s.products[i].ID == id {
+			return &s.products[i]
+		}
+	}
+	return nil
+}
+
+// likable はいいねできる（削除されていない）商品を返す。無ければ ErrNotFound。呼び出し側で mu を持っていること
+func (s *Store) likable(productID int) (*models.Product, error) {
+	p := s.product(productID)
+	if p == nil || p.DeletedAt != nil {
+		return nil, repository.ErrNotFound
+	}
+	return p, nil
+}
+
+// reservation は商品について期限内の承諾済みの提示を返す。呼び出し側で mu を持っていること
+func (s *Store) reservation(productID int) *models.Offer {
+	now := s.now()
+	for i := range s.offers {
+		o := &s.offers[i]
+		if o.ProductID == productID && o.Status == models.OfferAccepted && o.Live(now) {
+			return o
+		}
+	}
+	return nil
+}
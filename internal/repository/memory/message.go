@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"context"
+)
+
+type MessageRepository struct {
+	s *Store
+}
+
+func (r *MessageRepository) Create(ctx context.Context, m *models.Message) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	m.ID = len(r.s.messages) + 1
+	m.CreatedAt = r.s.now()
+	r.s.messages = append(r.s.messages, *m)
+	return nil
+}
+
+func (r *MessageRepository) ListThread(ctx context.Context, productID int, user1, user2 string) ([]models.Message, error) {
+	return r.ListThreadAfter(ctx, productID, user1, user2, 0)
+}
+
+func (r *MessageRepository) ListThreadAfter(ctx context.Context, productID int, user1, user2 string, afterID int) ([]models.Message, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var messages []models.Message
+	for _, m := range r.s.messages {
+		if m.ProductID != productID || m.ID <= afterID {
+			continue
+		}
+		if (m.SenderID == user1 && m.ReceiverID == user2) || (m.SenderID == user2 && m.ReceiverID == user1) {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"slices"
+)
+
+type NotificationRepository struct {
+	s *Store
+}
+
+func (r *NotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	n.ID = len(r.s.notifications) + 1
+	n.CreatedAt = r.s.now()
+	r.s.notifications = append(r.s.notifications, *n)
+	return nil
+}
+
+func (r *NotificationRepository) List(ctx context.Context, userID string, q repository.NotificationQuery) (*models.NotificationPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorNotifications)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	page := &models.NotificationPage{Items: []models.Notification{}}
+	for i := len(r.s.notifications) - 1; i >= 0; i-- {
+		n := r.s.notifications[i]
+		if n.UserID != userID {
+			continue
+		}
+		if n.ReadAt == nil {
+			page.UnreadCount++
+		}
+		if (q.UnreadOnly && n.ReadAt != nil) || (cursor != nil && n.ID >= cursor.ID) {
+			continue
+		}
+		page.Items = append(page.Items, n)
+	}
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = repository.Cursor{Sort: repository.CursorNotifications, ID: page.Items[q.Limit-1].ID}.Encode()
+	}
+	return page, nil
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID string, ids []int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.now()
+	for i := range r.s.notifications {
+		n := &r.s.notifications[i]
+		if n.UserID == userID && n.ReadAt == nil && (len(ids) == 0 || slices.Contains(ids, n.ID)) {
+			n.ReadAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *NotificationRepository) Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	prefs := models.DefaultNotificationPreferences()
+	for ch, kinds := range r.s.prefs[userID] {
+		for kind, enabled := range kinds {
+			if prefs[ch] != nil {
+				prefs[ch][kind] = enabled
+			}
+		}
+	}
+	return prefs, nil
+}
+
+func (r *NotificationRepository) SetPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	saved := r.s.prefs[userID]
+	if saved == nil {
+		saved = models.NotificationPreferences{}
+		r.s.prefs[userID] = saved
+	}
+	for ch, kinds := range prefs {
+		if saved[ch] == nil {
+			saved[ch] = map[models.NotificationKind]bool{}
+		}
+		for kind, enabled := range kinds {
+			saved[ch][kind] = enabled
+		}
+	}
+	return nil
+}
+
+type PushSubscriptionRepository struct {
+	s *Store
+}
+
+func (r *PushSubscriptionRepository) Save(ctx context.Context, sub *models.PushSubscription) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if existing, ok := r.s.pushSubs[sub.Endpoint]; ok {
+		sub.CreatedAt = existing.CreatedAt
+	} else {
+		sub.CreatedAt = r.s.now()
+	}
+	r.s.pushSubs[sub.Endpoint] = *sub
+	return nil
+}
+
+func (r *PushSubscriptionRepository) Delete(ctx context.Context, userID, endpoint string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if sub, ok := r.s.pushSubs[endpoint]; ok && sub.UserID == userID {
+		delete(r.s.pushSubs, endpoint)
+	}
+	return nil
+}
+
+func (r *PushSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]models.PushSubscription, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	subs := []models.PushSubscription{}
+	for _, sub := range r.s.pushSubs {
+		if sub.UserID == userID {
+			subs = append(subs, sub)
+		}
+	}
+	slices.SortFunc(subs, func(a, b models.PushSubscription) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return subs, nil
+}
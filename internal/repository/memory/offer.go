@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+)
+
+type OfferRepository struct {
+	s *Store
+}
+
+func (r *OfferRepository) Create(ctx context.Context, o *models.Offer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p := r.s.product(o.ProductID)
+	switch {
+	case p == nil || p.DeletedAt != nil:
+		return repository.ErrNotFound
+	case p.SellerID == o.BuyerID:
+		return repository.ErrSelfPurchase
+	case p.IsSold:
+		return repository.ErrSoldOut
+	case p.IsWithdrawn:
+		return repository.ErrWithdrawn
+	}
+	now := r.s.now()
+	for _, existing := range r.s.offers {
+		if existing.ProductID == o.ProductID && existing.BuyerID == o.BuyerID &&
+			existing.Status == models.OfferPending && existing.Live(now) {
+			return repository.ErrAlreadyExists
+		}
+	}
+
+	o.SellerID = p.SellerID
+	o.ProposedBy = models.RoleBuyer
+	o.Status = models.OfferPending
+	r.s.insertOffer(o)
+	return nil
+}
+
+// insertOffer は ID と日時を埋めて追加する。呼び出し側で mu を持っていること
+func (s *Store) insertOffer(o *models.Offer) {
+	now := s.now()
+	o.ID = len(s.offers) + 1
+	o.CreatedAt, o.UpdatedAt = now, now
+	s.offers = append(s.offers, *o)
+}
+
+func (r *OfferRepository) Get(ctx context.Context, id int) (*models.Offer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if id < 1 || id > len(r.s.offers) {
+		return nil, repository.ErrNotFound
+	}
+	o := r.s.offers[id-1]
+	return &o, nil
+}
+
+func (r *OfferRepository) ListByProduct(ctx context.Context, productID int, userID string) ([]models.Offer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	offers := []models.Offer{}
+	for _, o := range r.s.offers {
+		if o.ProductID == productID && (o.SellerID == userID || o.BuyerID == userID) {
+			offers = append(offers, o)
+		}
+	}
+	return offers, nil
+}
+
+func (r *OfferRepository) Respond(ctx context.Context, id int, actorID string, reply repository.OfferReply) (*models.Offer, *models.Offer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if id < 1 || id > len(r.s.offers) {
+		return nil, nil, repository.ErrNotFound
+	}
+	o := &r.s.offers[id-1]
+	if o.RoleOf(actorID) == "" {
+		return nil, nil, repository.ErrNotFound
+	}
+	now := r.s.now()
+	if !o.Live(now) {
+		return nil, nil, repository.ErrInvalidTransition
+	}
+	if !o.Allows(actorID, reply.Action) {
+		return nil, nil, repository.ErrForbidden
+	}
+
+	var next *models.Offer
+	switch reply.Action {
+	case models.OfferAccept:
+		p := r.s.product(o.ProductID)
+		switch {
+		case p == nil || p.DeletedAt != nil:
+			return nil, nil, repository.ErrNotFound
+		case p.IsSold:
+			return nil, nil, repository.ErrSoldOut
+		case p.IsWithdrawn:
+			return nil, nil, repository.ErrWithdrawn
+		case r.s.reservation(o.ProductID) != nil:
+			return nil, nil, repository.ErrReserved
+		}
+		o.Status = models.OfferAccepted
+		o.ExpiresAt = reply.ExpiresAt
+	case models.OfferReject:
+		o.Status = models.OfferRejected
+	case models.OfferCancel:
+		o.Status = models.OfferCancelled
+	case models.OfferCounter:
+		o.Status = models.OfferCountered
+		parentID := o.ID
+		next = &models.Offer{
+			ProductID:  o.ProductID,
+			BuyerID:    o.BuyerID,
+			SellerID:   o.SellerID,
+			ProposedBy: o.RoleOf(actorID),
+			Price:      reply.Price,
+			Status:     models.OfferPending,
+			ParentID:   &parentID,
+			ExpiresAt:  reply.ExpiresAt,
+		}
+	}
+	o.UpdatedAt = now
+	updated := *o
+	if next != nil {
+		// append で r.s.offers が移動するので o はこれ以降使わない
+		r.s.insertOffer(next)
+	}
+	return &updated, next, nil
+}
+
+func (r *OfferRepository) Expire(ctx context.Context) ([]models.Offer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := r.s.now()
+	expired := []models.Offer{}
+	for i := range r.s.offers {
+		o := &r.s.offers[i]
+		before := o.Status
+		if o.Refresh(now); o.Status != before {
+			o.UpdatedAt = now
+			expired = append(expired, *o)
+		}
+	}
+	return expired, nil
+}
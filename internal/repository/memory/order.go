@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"time"
+)
+
+type OrderRepository struct {
+	s *Store
+}
+
+func (r *OrderRepository) Purchase(ctx context.Context, productID int, buyerID string) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p := r.s.product(productID)
+	switch {
+	case p == nil || p.DeletedAt != nil:
+		return nil, repository.ErrNotFound
+	case p.SellerID == buyerID:
+		return nil, repository.ErrSelfPurchase
+	case p.IsSold:
+		return nil, repository.ErrSoldOut
+	case p.IsWithdrawn:
+		return nil, repository.ErrWithdrawn
+	}
+	price := p.Price
+	if reserved := r.s.reservation(p.ID); reserved != nil {
+		if reserved.BuyerID != buyerID {
+			return nil, repository.ErrReserved
+		}
+		price = reserved.Price
+		reserved.Status = models.OfferPurchased
+		reserved.UpdatedAt = r.s.now()
+	}
+	p.IsSold = true
+
+	now := r.s.now()
+	o := models.Order{
+		ID:        len(r.s.orders) + 1,
+		ProductID: p.ID,
+		BuyerID:   buyerID,
+		SellerID:  p.SellerID,
+		Price:     price,
+		Status:    models.OrderPendingPayment,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.s.orders = append(r.s.orders, o)
+	return &o, nil
+}
+
+func (r *OrderRepository) Get(ctx context.Context, id int) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if id < 1 || id > len(r.s.orders) {
+		return nil, repository.ErrNotFound
+	}
+	o := r.s.orders[id-1]
+	return &o, nil
+}
+
+func (r *OrderRepository) ListByUser(ctx context.Context, userID string, role models.OrderRole) ([]models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	orders := []models.Order{}
+	for i := len(r.s.orders) - 1; i >= 0; i-- {
+		o := r.s.orders[i]
+		if got := o.RoleOf(userID); got != "" && (role == "" || got == role) {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (r *OrderRepository) Transition(ctx context.Context, id int, actorID string, to models.OrderStatus) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if id < 1 || id > len(r.s.orders) {
+		return nil, repository.ErrNotFound
+	}
+	o := &r.s.orders[id-1]
+	role := o.RoleOf(actorID)
+	if role == "" {
+		return nil, repository.ErrNotFound
+	}
+	if !models.CanTransition(o.Status, to, role) {
+		return nil, repository.ErrInvalidTransition
+	}
+
+	o.Status = to
+	o.UpdatedAt = r.s.now()
+	if to.Relists() {
+		r.s.relist(o)
+	}
+	cp := *o
+	return &cp, nil
+}
+
+func (r *OrderRepository) ExpireUnpaid(ctx context.Context, before time.Time) ([]models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	expired := []models.Order{}
+	for i := range r.s.orders {
+		o := &r.s.orders[i]
+		if o.Status != models.OrderPendingPayment || !o.CreatedAt.Before(before) {
+			continue
+		}
+		o.Status = models.OrderCancelled
+		o.UpdatedAt = r.s.now()
+		r.s.relist(o)
+		expired = append(expired, *o)
+	}
+	return expired, nil
+}
+
+// relist は注文の商品を再び購入できるようにし、承諾価格で買った提示を終わった状態にする。r.s.mu を持って呼ぶ
+func (s *Store) relist(o *models.Order) {
+	if p := s.product(o.ProductID); p != nil {
+		p.IsSold = false
+	}
+	for i := range s.offers {
+		if of := &s.offers[i]; of.ProductID == o.ProductID && of.BuyerID == o.BuyerID && of.Status == models.OfferPurchased {
+			of.Status = models.OfferCancelled
+			of.UpdatedAt = o.UpdatedAt
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ProductRepository struct {
+	s *Store
+}
+
+func (r *ProductRepository) List(ctx context.Context, q repository.ProductQuery) (*models.ProductPage, error) {
+	less, ok := productLess[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	cursor, err := repository.DecodeCursor(q.Cursor, string(q.Sort))
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var matched []models.Product
+	for _, p := range r.s.products {
+		if !matchesFilters(&p, q) {
+			continue
+		}
+		p.Description = ""
+		matched = append(matched, p)
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(&matched[i], &matched[j]) })
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	for _, p := range matched {
+		// カーソル位置の商品より後ろに並ぶものだけを返す
+		if cursor != nil && !less(&models.Product{ID: cursor.ID, Price: int(cursor.Value), LikeCount: int(cursor.Value)}, &p) {
+			continue
+		}
+		if len(page.Items) == q.Limit {
+			last := page.Items[q.Limit-1]
+			next := repository.Cursor{Sort: string(q.Sort), ID: last.ID}
+			switch q.Sort {
+			case repository.SortPriceAsc, repository.SortPriceDesc:
+				next.Value = float64(last.Price)
+			case repository.SortMostLiked:
+				next.Value = float64(last.LikeCount)
+			}
+			page.NextCursor = next.Encode()
+			break
+		}
+		page.Items = append(page.Items, p)
+	}
+	return page, nil
+}
+
+func matchesFilters(p *models.Product, q repository.ProductQuery) bool {
+	switch {
+	case p.DeletedAt != nil,
+		p.IsWithdrawn && !q.IncludeWithdrawn,
+		q.MinPrice != nil && p.Price < *q.MinPrice,
+		q.MaxPrice != nil && p.Price > *q.MaxPrice,
+		q.Sold != nil && p.IsSold != *q.Sold,
+		q.SellerID != "" && p.SellerID != q.SellerID:
+		return false
+	}
+	return true
+}
+
+// Search は MySQL の FULLTEXT の代わりに、検索語の出現回数を関連度とする（タイトルは2倍）
+func (r *ProductRepository) Search(ctx context.Context, text string, q repository.ProductQuery) (*models.ProductPage, error) {
+	sortKey := string(repository.SortRelevance)
+	cursor, err := repository.DecodeCursor(q.Cursor, sortKey)
+	if err != nil {
+		return nil, err
+	}
+	terms := strings.Fields(strings.ToLower(text))
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	type hit struct {
+		p     models.Product
+		score int
+	}
+	var hits []hit
+	for _, p := range r.s.products {
+		if !matchesFilters(&p, q) {
+			continue
+		}
+		score := 0
+		for _, term := range terms {
+			score += 2*strings.Count(strings.ToLower(p.Title), term) + strings.Count(strings.ToLower(p.Description), term)
+		}
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, hit{p, score})
+	}
+	after := func(a, b hit) bool {
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.p.ID > b.p.ID
+	}
+	sort.Slice(hits, func(i, j int) bool { return after(hits[i], hits[j]) })
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	var last hit
+	for _, h := range hits {
+		if cursor != nil && !after(hit{models.Product{ID: cursor.ID}, int(cursor.Value)}, h) {
+			continue
+		}
+		if len(page.Items) == q.Limit {
+			page.NextCursor = repository.Cursor{Sort: sortKey, Value: float64(last.score), ID: last.p.ID}.Encode()
+			break
+		}
+		page.Items = append(page.Items, h.p)
+		last = h
+	}
+	return page, nil
+}
+
+// productLess は並び順ごとに a が b より前に来るかを返す（MySQL 実装の ORDER BY と同じ順）
+var productLess = map[repository.ProductSort]func(a, b *models.Product) bool{
+	repository.SortNewest: func(a, b *models.Product) bool { return a.ID > b.ID },
+	repository.SortPriceAsc: func(a, b *models.Product) bool {
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+		return a.ID < b.ID
+	},
+	repository.SortPriceDesc: func(a, b *models.Product) bool {
+		if a.Price != b.Price {
+			return a.Price > b.Price
+		}
+		return a.ID > b.ID
+	},
+	repository.SortMostLiked: func(a, b *models.Product) bool {
+		if a.LikeCount != b.LikeCount {
+			return a.LikeCount > b.LikeCount
+		}
+		return a.ID > b.ID
+	},
+}
+
+func (r *ProductRepository) Get(ctx context.Context, id int) (*models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p := r.s.product(id)
+	if p == nil || p.DeletedAt != nil {
+		return nil, repository.ErrNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *ProductRepository) Create(ctx context.Context, p *models.Product) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p.ID = len(r.s.products) + 1
+	p.IsSold = false
+	p.IsWithdrawn = false
+	p.Version = 1
+	p.CreatedAt = r.s.now()
+	r.s.products = append(r.s.products, *p)
+	return nil
+}
+
+func (r *ProductRepository) ListBySeller(ctx context.Context, sellerID string, q repository.PageQuery) (*models.ProductPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorSelling)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	// products は ID 順なので後ろから読めば新しい順
+	for i := len(r.s.products) - 1; i >= 0; i-- {
+		p := r.s.products[i]
+		if p.SellerID != sellerID || p.IsWithdrawn || p.DeletedAt != nil || (cursor != nil && p.ID >= cursor.ID) {
+			continue
+		}
+		if len(page.Items) == q.Limit {
+			page.NextCursor = repository.Cursor{Sort: repository.CursorSelling, ID: page.Items[q.Limit-1].ID}.Encode()
+			break
+		}
+		p.Description = ""
+		page.Items = append(page.Items, p)
+	}
+	return page, nil
+}
+
+// owned は sellerID の商品のポインタを返す。呼び出し側で mu を持っていること
+func (r *ProductRepository) owned(id int, sellerID string) (*models.Product, error) {
+	p := r.s.product(id)
+	switch {
+	case p == nil || p.DeletedAt != nil:
+		return nil, repository.ErrNotFound
+	case p.SellerID != sellerID:
+		return nil, repository.ErrForbidden
+	case p.IsSold:
+		return nil, repository.ErrSoldOut
+	}
+	return p, nil
+}
+
+func (r *ProductRepository) Update(ctx context.Context, id int, sellerID string, version int, patch repository.ProductPatch) (*models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, err := r.owned(id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Version != version {
+		return nil, repository.ErrConflict
+	}
+	patch.Apply(p)
+	p.Version++
+	cp := *p
+	return &cp, nil
+}
+
+func (r *ProductRepository) SetWithdrawn(ctx context.Context, id int, sellerID string, withdrawn bool) (*models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, err := r.owned(id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsWithdrawn != withdrawn {
+		p.IsWithdrawn = withdrawn
+		p.Version++
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *ProductRepository) Delete(ctx context.Context, id int, sellerID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, err := r.owned(id, sellerID)
+	if err != nil {
+		return err
+	}
+	now := r.s.now()
+	p.DeletedAt = &now
+	p.Version++
+	return nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+)
+
+type ReviewRepository struct {
+	s *Store
+}
+
+func (r *ReviewRepository) Create(ctx context.Context, rv *models.Review) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.reviews {
+		if existing.OrderID == rv.OrderID && existing.ReviewerID == rv.ReviewerID {
+			return repository.ErrAlreadyExists
+		}
+	}
+	rv.ID = len(r.s.reviews) + 1
+	rv.CreatedAt = r.s.now()
+	r.s.reviews = append(r.s.reviews, *rv)
+	return nil
+}
+
+func (r *ReviewRepository) ListByReviewee(ctx context.Context, userID string, role models.OrderRole) ([]models.Review, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	reviews := []models.Review{}
+	for i := len(r.s.reviews) - 1; i >= 0; i-- {
+		rv := r.s.reviews[i]
+		if rv.RevieweeID != userID || (role != "" && rv.ReviewerRole != role) {
+			continue
+		}
+		rv.Reviewer = models.UserSummary{ID: rv.ReviewerID}
+		if u, ok := r.s.users[rv.ReviewerID]; ok {
+			rv.Reviewer.Name, rv.Reviewer.AvatarURL = u.Name, u.AvatarURL
+		}
+		reviews = append(reviews, rv)
+	}
+	return reviews, nil
+}
+
+func (r *ReviewRepository) Summary(ctx context.Context, userID string) (models.RatingSummary, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var s models.RatingSummary
+	for _, rv := range r.s.reviews {
+		if rv.RevieweeID != userID {
+			continue
+		}
+		switch rv.Rating {
+		case models.RatingGood:
+			s.Good++
+		case models.RatingNormal:
+			s.Normal++
+		case models.RatingBad:
+			s.Bad++
+		}
+		s.Total++
+	}
+	return s, nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+func (r *UserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) Upsert(ctx context.Context, u *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if existing, ok := r.s.users[u.ID]; ok {
+		existing.Name = u.Name
+		existing.Email = u.Email
+		existing.AvatarURL = u.AvatarURL
+		r.s.users[u.ID] = existing
+		return nil
+	}
+	created := *u
+	created.CreatedAt = r.s.now()
+	r.s.users[u.ID] = created
+	return nil
+}
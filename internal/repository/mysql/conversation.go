@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+)
+
+type ConversationRepository struct {
+	db *sql.DB
+}
+
+func (r *ConversationRepository) List(ctx context.Context, userID string, q repository.PageQuery) (*models.ConversationPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorConversations)
+	if err != nil {
+		return nil, err
+	}
+
+	// t で (商品, 相手) ごとの最後のメッセージIDを求め、本文・相手・商品・既読位置を結合する
+	query := `
+		SELECT m.id, m.product_id, m.sender_id, m.receiver_id, m.content, m.created_at,
+			t.partner_id, COALESCE(u.name, ''), COALESCE(u.avatar_url, ''),
+			COALESCE(p.title, ''), COALESCE(p.price, 0), COALESCE(p.image_key, ''), COALESCE(p.is_sold, FALSE),
+			(SELECT COUNT(*) FROM messages x
+				WHERE x.product_id = t.product_id AND x.sender_id = t.partner_id AND x.receiver_id = ?
+				AND x.id > COALESCE(cr.last_read_message_id, 0)) AS unread_count
+		FROM (
+			SELECT product_id, IF(sender_id = ?, receiver_id, sender_id) AS partner_id, MAX(id) AS last_id
+			FROM messages
+			WHERE sender_id = ? OR receiver_id = ?
+			GROUP BY product_id, partner_id
+		) t
+		JOIN messages m ON m.id = t.last_id
+		LEFT JOIN users u ON u.id = t.partner_id
+		LEFT JOIN products p ON p.id = t.product_id
+		LEFT JOIN conversation_reads cr
+			ON cr.user_id = ? AND cr.product_id = t.product_id AND cr.partner_id = t.partner_id`
+	args := []any{userID, userID, userID, userID, userID}
+	if cursor != nil {
+		query += " WHERE m.id < ?"
+		args = append(args, cursor.ID)
+	}
+	query += " ORDER BY m.id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.ConversationPage{Items: []models.Conversation{}}
+	for rows.Next() {
+		var c models.Conversation
+		m := &c.LastMessage
+		if err := rows.Scan(&m.ID, &m.ProductID, &m.SenderID, &m.ReceiverID, &m.Content, &m.CreatedAt,
+			&c.Partner.ID, &c.Partner.Name, &c.Partner.AvatarURL,
+			&c.Product.Title, &c.Product.Price, &c.Product.ImageKey, &c.Product.IsSold,
+			&c.UnreadCount); err != nil {
+			return nil, err
+		}
+		c.Product.ID = m.ProductID
+		c.LastMessageAt = m.CreatedAt
+		page.Items = append(page.Items, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Limit > 0 && len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = repository.Cursor{Sort: repository.CursorConversations, ID: page.Items[q.Limit-1].LastMessage.ID}.Encode()
+	}
+	return page, nil
+}
+
+func (r *ConversationRepository) MarkRead(ctx context.Context, userID string, productID int, partnerID string, upToID int) error {
+	if upToID == 0 {
+		err := r.db.QueryRowContext(ctx,
+			"SELECT COALESCE(MAX(id), 0) FROM messages WHERE product_id = ? AND sender_id = ? AND receiver_id = ?",
+			productID, partnerID, userID,
+		).Scan(&upToID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO conversation_reads (user_id, product_id, partner_id, last_read_message_id)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE last_read_message_id = GREATEST(last_read_message_id, ?)`,
+		userID, productID, partnerID, upToID, upToID,
+	)
+	return err
+}
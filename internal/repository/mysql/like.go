@@ -0,0 +1,193 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+type LikeRepository struct {
+	db *sql.DB
+}
+
+// Toggle は商品の行をロックしてから状態を読むので、同じ商品への切り替えが重なっても
+// 2回とも「未いいね」と判断して INSERT が重複することはない
+func (r *LikeRepository) Toggle(ctx context.Context, userID string, productID int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := lockLikable(ctx, tx, productID); err != nil {
+		return false, err
+	}
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND product_id = ?)", userID, productID).
+		Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		_, err = unlike(ctx, tx, userID, productID)
+	} else {
+		_, err = like(ctx, tx, userID, productID)
+	}
+	if err != nil {
+		return false, err
+	}
+	return !exists, tx.Commit()
+}
+
+func (r *LikeRepository) Like(ctx context.Context, userID string, productID int) (bool, error) {
+	return r.set(ctx, userID, productID, like)
+}
+
+func (r *LikeRepository) Unlike(ctx context.Context, userID string, productID int) (bool, error) {
+	return r.set(ctx, userID, productID, unlike)
+}
+
+func (r *LikeRepository) set(ctx context.Context, userID string, productID int, apply func(context.Context, *sql.Tx, string, int) (bool, error)) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := lockLikable(ctx, tx, productID); err != nil {
+		return false, err
+	}
+	changed, err := apply(ctx, tx, userID, productID)
+	if err != nil {
+		return false, err
+	}
+	return changed, tx.Commit()
+}
+
+// lockLikable は削除されていない商品の行を FOR UPDATE でロックする。無ければ ErrNotFound。
+// いいねの操作はどれもこの行を先にロックするので、同じ商品への操作は順に実行される
+func lockLikable(ctx context.Context, tx *sql.Tx, productID int) error {
+	err := tx.QueryRowContext(ctx, "SELECT id FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", productID).Scan(&productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// like は (user_id, product_id) の主キーに任せて INSERT IGNORE し、実際に増えたときだけいいね数を足す
+func like(ctx context.Context, tx *sql.Tx, userID string, productID int) (bool, error) {
+	res, err := tx.ExecContext(ctx, "INSERT IGNORE INTO likes (user_id, product_id) VALUES (?, ?)", userID, productID)
+	if err != nil {
+		return false, err
+	}
+	return addLikeCount(ctx, tx, productID, res, 1)
+}
+
+func unlike(ctx context.Context, tx *sql.Tx, userID string, productID int) (bool, error) {
+	res, err := tx.ExecContext(ctx, "DELETE FROM likes WHERE user_id = ? AND product_id = ?", userID, productID)
+	if err != nil {
+		return false, err
+	}
+	return addLikeCount(ctx, tx, productID, res, -1)
+}
+
+// addLikeCount はいいね数を実際に増減した行数だけ動かし、行が変わったかを返す
+func addLikeCount(ctx context.Context, tx *sql.Tx, productID int, res sql.Result, sign int64) (bool, error) {
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET like_count = like_count + ? WHERE id = ?", sign*n, productID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *LikeRepository) Exists(ctx context.Context, userID string, productID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND product_id = ?)", userID, productID).
+		Scan(&exists)
+	return exists, err
+}
+
+func (r *LikeRepository) Statuses(ctx context.Context, userID string, productIDs []int) (map[int]models.LikeStatus, error) {
+	statuses := make(map[int]models.LikeStatus, len(productIDs))
+	if len(productIDs) == 0 {
+		return statuses, nil
+	}
+	args := []any{userID}
+	for _, id := range productIDs {
+		args = append(args, id)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.id, p.like_count,
+			EXISTS(SELECT 1 FROM likes l WHERE l.user_id = ? AND l.product_id = p.id)
+		FROM products p
+		WHERE p.deleted_at IS NULL AND p.id IN (?`+strings.Repeat(", ?", len(productIDs)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st models.LikeStatus
+		if err := rows.Scan(&st.ProductID, &st.LikeCount, &st.LikedByMe); err != nil {
+			return nil, err
+		}
+		statuses[st.ProductID] = st
+	}
+	return statuses, rows.Err()
+}
+
+func (r *LikeRepository) ListLikedProducts(ctx context.Context, userID string, q repository.PageQuery) (*models.ProductPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorLiked)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + listColumns + `, l.created_at
+		FROM likes l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.user_id = ? AND p.deleted_at IS NULL AND p.is_withdrawn = FALSE`
+	args := []any{userID}
+	if cursor != nil {
+		// created_at は秒単位なので、同じ秒のいいねは商品IDで並べる
+		likedAt := time.Unix(int64(cursor.Value), 0).UTC()
+		query += " AND (l.created_at < ? OR (l.created_at = ? AND l.product_id < ?))"
+		args = append(args, likedAt, likedAt, cursor.ID)
+	}
+	query += " ORDER BY l.created_at DESC, p.id DESC LIMIT ?"
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	var likedAt []time.Time
+	for rows.Next() {
+		var p models.Product
+		var at time.Time
+		if err := rows.Scan(&p.ID, &p.SellerID, &p.Title, &p.Price, &p.ImageURL, &p.ImageKey, &p.IsSold, &p.CreatedAt, &p.Version, &p.IsWithdrawn, &p.LikeCount, &at); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, p)
+		likedAt = append(likedAt, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := page.Items[q.Limit-1]
+		page.NextCursor = repository.Cursor{Sort: repository.CursorLiked, Value: float64(likedAt[q.Limit-1].Unix()), ID: last.ID}.Encode()
+	}
+	return page, nil
+}
@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type MessageRepository struct {
+	db *sql.DB
+}
+
+const messageColumns = "id, product_id, sender_id, receiver_id, content, created_at"
+
+func scanMessage(row scanner, m *models.Message) error {
+	return row.Scan(&m.ID, &m.ProductID, &m.SenderID, &m.ReceiverID, &m.Content, &m.CreatedAt)
+}
+
+func (r *MessageRepository) Create(ctx context.Context, m *models.Message) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO messages (product_id, sender_id, receiver_id, content) VALUES (?, ?, ?, ?)",
+		m.ProductID, m.SenderID, m.ReceiverID, m.Content,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(id)
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM messages WHERE id = ?", id).Scan(&m.CreatedAt)
+}
+
+func (r *MessageRepository) ListThread(ctx context.Context, productID int, user1, user2 string) ([]models.Message, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE product_id = ?
+		AND ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?))
+		ORDER BY created_at ASC, id ASC`,
+		productID, user1, user2, user2, user1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectMessages(rows)
+}
+
+func (r *MessageRepository) ListThreadAfter(ctx context.Context, productID int, user1, user2 string, afterID int) ([]models.Message, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE product_id = ? AND id > ?
+		AND ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?))
+		ORDER BY id ASC`,
+		productID, afterID, user1, user2, user2, user1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectMessages(rows)
+}
+
+func collectMessages(rows *sql.Rows) ([]models.Message, error) {
+	defer rows.Close()
+	var messages []models.Message
+	for rows.Next() {
+		var m models.Message
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,28 @@
+// Package mysql は repository のインターフェースを MySQL で実装する
+package mysql
+
+import (
+	"backend/internal/repository"
+	"database/sql"
+)
+
+// scanner は *sql.Row と *sql.Rows の共通部分
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// NewRepositories は db を使うリポジトリ一式を作る
+func NewRepositories(db *sql.DB) repository.Repositories {
+	return repository.Repositories{
+		Products:          &ProductRepository{db: db},
+		Users:             &UserRepository{db: db},
+		Likes:             &LikeRepository{db: db},
+		Messages:          &MessageRepository{db: db},
+		Conversations:     &ConversationRepository{db: db},
+		Orders:            &OrderRepository{db: db},
+		Reviews:           &ReviewRepository{db: db},
+		Offers:            &OfferRepository{db: db},
+		Notifications:     &NotificationRepository{db: db},
+		PushSubscriptions: &PushSubscriptionRepository{db: db},
+	}
+}
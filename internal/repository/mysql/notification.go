@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"strings"
+)
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+const notificationColumns = "id, user_id, kind, actor_id, product_id, order_id, message_id, title, body, read_at, created_at"
+
+func scanNotification(row scanner, n *models.Notification) error {
+	var readAt sql.NullTime
+	if err := row.Scan(&n.ID, &n.UserID, &n.Kind, &n.ActorID, &n.ProductID, &n.OrderID, &n.MessageID, &n.Title, &n.Body, &readAt, &n.CreatedAt); err != nil {
+		return err
+	}
+	n.ReadAt = nil
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+	}
+	return nil
+}
+
+func (r *NotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO notifications (user_id, kind, actor_id, product_id, order_id, message_id, title, body) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		n.UserID, n.Kind, n.ActorID, n.ProductID, n.OrderID, n.MessageID, n.Title, n.Body)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return scanNotification(r.db.QueryRowContext(ctx, "SELECT "+notificationColumns+" FROM notifications WHERE id = ?", id), n)
+}
+
+func (r *NotificationRepository) List(ctx context.Context, userID string, q repository.NotificationQuery) (*models.NotificationPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorNotifications)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = ?"
+	args := []any{userID}
+	if q.UnreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	if cursor != nil {
+		query += " AND id < ?"
+		args = append(args, cursor.ID)
+	}
+	// 次のページがあるかを知るために1件多く取る
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.NotificationPage{Items: []models.Notification{}}
+	for rows.Next() {
+		var n models.Notification
+		if err := scanNotification(rows, &n); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = repository.Cursor{Sort: repository.CursorNotifications, ID: page.Items[q.Limit-1].ID}.Encode()
+	}
+
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&page.UnreadCount)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID string, ids []int) error {
+	query := "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL"
+	args := []any{userID}
+	if len(ids) > 0 {
+		query += " AND id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *NotificationRepository) Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT channel, kind, enabled FROM notification_preferences WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := models.DefaultNotificationPreferences()
+	for rows.Next() {
+		var ch models.NotificationChannel
+		var kind models.NotificationKind
+		var enabled bool
+		if err := rows.Scan(&ch, &kind, &enabled); err != nil {
+			return nil, err
+		}
+		if prefs[ch] != nil {
+			prefs[ch][kind] = enabled
+		}
+	}
+	return prefs, rows.Err()
+}
+
+func (r *NotificationRepository) SetPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for ch, kinds := range prefs {
+		for kind, enabled := range kinds {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO notification_preferences (user_id, channel, kind, enabled) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)",
+				userID, ch, kind, enabled)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+type PushSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func (r *PushSubscriptionRepository) Save(ctx context.Context, s *models.PushSubscription) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO push_subscriptions (endpoint, user_id, p256dh, auth) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), p256dh = VALUES(p256dh), auth = VALUES(auth)`,
+		s.Endpoint, s.UserID, s.P256dh, s.Auth)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM push_subscriptions WHERE endpoint = ?", s.Endpoint).Scan(&s.CreatedAt)
+}
+
+func (r *PushSubscriptionRepository) Delete(ctx context.Context, userID, endpoint string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM push_subscriptions WHERE user_id = ? AND endpoint = ?", userID, endpoint)
+	return err
+}
+
+func (r *PushSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]models.PushSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT endpoint, user_id, p256dh, auth, created_at FROM push_subscriptions WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.PushSubscription{}
+	for rows.Next() {
+		var s models.PushSubscription
+		if err := rows.Scan(&s.Endpoint, &s.UserID, &s.P256dh, &s.Auth, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
@@ -0,0 +1,261 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type OfferRepository struct {
+	db *sql.DB
+}
+
+const offerColumns = "id, product_id, buyer_id, seller_id, proposed_by, price, status, parent_id, expires_at, created_at, updated_at"
+
+func scanOffer(row scanner, o *models.Offer) error {
+	var parentID sql.NullInt64
+	if err := row.Scan(&o.ID, &o.ProductID, &o.BuyerID, &o.SellerID, &o.ProposedBy, &o.Price, &o.Status, &parentID, &o.ExpiresAt, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		return err
+	}
+	o.ParentID = nil
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		o.ParentID = &id
+	}
+	return nil
+}
+
+func scanOffers(rows *sql.Rows) ([]models.Offer, error) {
+	defer rows.Close()
+	offers := []models.Offer{}
+	for rows.Next() {
+		var o models.Offer
+		if err := scanOffer(rows, &o); err != nil {
+			return nil, err
+		}
+		offers = append(offers, o)
+	}
+	return offers, rows.Err()
+}
+
+// lockListed は提示や承諾の前に商品行をロックし、購入できる状態かを確かめる
+func lockListed(ctx context.Context, tx *sql.Tx, productID int) (sellerID string, err error) {
+	var isSold, isWithdrawn bool
+	err = tx.QueryRowContext(ctx, "SELECT seller_id, is_sold, is_withdrawn FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", productID).
+		Scan(&sellerID, &isSold, &isWithdrawn)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "", repository.ErrNotFound
+	case err != nil:
+		return "", err
+	case isSold:
+		return "", repository.ErrSoldOut
+	case isWithdrawn:
+		return "", repository.ErrWithdrawn
+	}
+	return sellerID, nil
+}
+
+// lockReservation は商品の期限内の承諾済み提示をロックして返す。無ければ nil。
+// 同時に2件承諾されないよう、呼び出し側で商品行を先にロックしておくこと
+func lockReservation(ctx context.Context, tx *sql.Tx, productID int, now time.Time) (*models.Offer, error) {
+	var o models.Offer
+	err := scanOffer(tx.QueryRowContext(ctx,
+		"SELECT "+offerColumns+" FROM offers WHERE product_id = ? AND status = ? AND expires_at > ? LIMIT 1 FOR UPDATE",
+		productID, models.OfferAccepted, now), &o)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func insertOffer(ctx context.Context, tx *sql.Tx, o *models.Offer) error {
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO offers (product_id, buyer_id, seller_id, proposed_by, price, status, parent_id, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		o.ProductID, o.BuyerID, o.SellerID, o.ProposedBy, o.Price, o.Status, o.ParentID, o.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return scanOffer(tx.QueryRowContext(ctx, "SELECT "+offerColumns+" FROM offers WHERE id = ?", id), o)
+}
+
+func (r *OfferRepository) Create(ctx context.Context, o *models.Offer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sellerID, err := lockListed(ctx, tx, o.ProductID)
+	if err != nil {
+		return err
+	}
+	if sellerID == o.BuyerID {
+		return repository.ErrSelfPurchase
+	}
+	// 商品行をロックしているので、同じ買い手の提示が同時に2件できることはない
+	var pending int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM offers WHERE product_id = ? AND buyer_id = ? AND status = ? AND expires_at > ?",
+		o.ProductID, o.BuyerID, models.OfferPending, time.Now()).Scan(&pending)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return repository.ErrAlreadyExists
+	}
+
+	o.SellerID = sellerID
+	o.ProposedBy = models.RoleBuyer
+	o.Status = models.OfferPending
+	if err := insertOffer(ctx, tx, o); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *OfferRepository) Get(ctx context.Context, id int) (*models.Offer, error) {
+	var o models.Offer
+	err := scanOffer(r.db.QueryRowContext(ctx, "SELECT "+offerColumns+" FROM offers WHERE id = ?", id), &o)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *OfferRepository) ListByProduct(ctx context.Context, productID int, userID string) ([]models.Offer, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+offerColumns+" FROM offers WHERE product_id = ? AND (seller_id = ? OR buyer_id = ?) ORDER BY id",
+		productID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanOffers(rows)
+}
+
+// Respond は商品行、提示の順にロックする（Purchase と同じ順番なのでデッドロックしない）
+func (r *OfferRepository) Respond(ctx context.Context, id int, actorID string, reply repository.OfferReply) (*models.Offer, *models.Offer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	err = tx.QueryRowContext(ctx, "SELECT product_id FROM offers WHERE id = ?", id).Scan(&productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// 商品が削除されていても拒否や取り消しはできるので、行が無いのはエラーにしない
+	err = tx.QueryRowContext(ctx, "SELECT id FROM products WHERE id = ? FOR UPDATE", productID).Scan(&productID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+
+	var o models.Offer
+	if err := scanOffer(tx.QueryRowContext(ctx, "SELECT "+offerColumns+" FROM offers WHERE id = ? FOR UPDATE", id), &o); err != nil {
+		return nil, nil, err
+	}
+	if o.RoleOf(actorID) == "" {
+		return nil, nil, repository.ErrNotFound
+	}
+	now := time.Now()
+	if !o.Live(now) {
+		return nil, nil, repository.ErrInvalidTransition
+	}
+	if !o.Allows(actorID, reply.Action) {
+		return nil, nil, repository.ErrForbidden
+	}
+
+	var next *models.Offer
+	expiresAt := o.ExpiresAt
+	switch reply.Action {
+	case models.OfferAccept:
+		if _, err := lockListed(ctx, tx, o.ProductID); err != nil {
+			return nil, nil, err
+		}
+		reserved, err := lockReservation(ctx, tx, o.ProductID, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reserved != nil {
+			return nil, nil, repository.ErrReserved
+		}
+		o.Status = models.OfferAccepted
+		expiresAt = reply.ExpiresAt
+	case models.OfferReject:
+		o.Status = models.OfferRejected
+	case models.OfferCancel:
+		o.Status = models.OfferCancelled
+	case models.OfferCounter:
+		o.Status = models.OfferCountered
+		parentID := o.ID
+		next = &models.Offer{
+			ProductID:  o.ProductID,
+			BuyerID:    o.BuyerID,
+			SellerID:   o.SellerID,
+			ProposedBy: o.RoleOf(actorID),
+			Price:      reply.Price,
+			Status:     models.OfferPending,
+			ParentID:   &parentID,
+			ExpiresAt:  reply.ExpiresAt,
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE offers SET status = ?, expires_at = ? WHERE id = ?", o.Status, expiresAt, o.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := scanOffer(tx.QueryRowContext(ctx, "SELECT "+offerColumns+" FROM offers WHERE id = ?", o.ID), &o); err != nil {
+		return nil, nil, err
+	}
+	if next != nil {
+		if err := insertOffer(ctx, tx, next); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &o, next, tx.Commit()
+}
+
+func (r *OfferRepository) Expire(ctx context.Context) ([]models.Offer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+offerColumns+" FROM offers WHERE status IN (?, ?) AND expires_at <= ? FOR UPDATE",
+		models.OfferPending, models.OfferAccepted, now)
+	if err != nil {
+		return nil, err
+	}
+	expired, err := scanOffers(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range expired {
+		if _, err := tx.ExecContext(ctx, "UPDATE offers SET status = ? WHERE id = ?", models.OfferExpired, expired[i].ID); err != nil {
+			return nil, err
+		}
+		expired[i].Status = models.OfferExpired
+		expired[i].UpdatedAt = now
+	}
+	return expired, tx.Commit()
+}
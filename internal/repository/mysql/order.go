@@ -0,0 +1,227 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type OrderRepository struct {
+	db *sql.DB
+}
+
+const orderColumns = "id, product_id, buyer_id, seller_id, price, status, created_at, updated_at"
+
+func scanOrder(row scanner, o *models.Order) error {
+	return row.Scan(&o.ID, &o.ProductID, &o.BuyerID, &o.SellerID, &o.Price, &o.Status, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// Purchase は商品行を FOR UPDATE でロックしてから売り切れにするので、
+// 同時に購入されても注文は1件だけ作られる
+func (r *OrderRepository) Purchase(ctx context.Context, productID int, buyerID string) (*models.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var o models.Order
+	var isSold, isWithdrawn bool
+	err = tx.QueryRowContext(ctx, "SELECT id, seller_id, price, is_sold, is_withdrawn FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", productID).
+		Scan(&o.ProductID, &o.SellerID, &o.Price, &isSold, &isWithdrawn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.SellerID == buyerID {
+		return nil, repository.ErrSelfPurchase
+	}
+	if isSold {
+		return nil, repository.ErrSoldOut
+	}
+	if isWithdrawn {
+		return nil, repository.ErrWithdrawn
+	}
+
+	// 価格提示が承諾されていれば、その買い手だけが承諾価格で買える
+	reserved, err := lockReservation(ctx, tx, o.ProductID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if reserved != nil {
+		if reserved.BuyerID != buyerID {
+			return nil, repository.ErrReserved
+		}
+		o.Price = reserved.Price
+		if _, err := tx.ExecContext(ctx, "UPDATE offers SET status = ? WHERE id = ?", models.OfferPurchased, reserved.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// ロック済みだが念のため条件付きで更新し、0件なら売り切れ扱いにする
+	res, err := tx.ExecContext(ctx, "UPDATE products SET is_sold = TRUE WHERE id = ? AND is_sold = FALSE", o.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return nil, repository.ErrSoldOut
+	}
+
+	res, err = tx.ExecContext(ctx, "INSERT INTO orders (product_id, buyer_id, seller_id, price, status) VALUES (?, ?, ?, ?, ?)",
+		o.ProductID, buyerID, o.SellerID, o.Price, models.OrderPendingPayment)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = ?", id), &o); err != nil {
+		return nil, err
+	}
+	return &o, tx.Commit()
+}
+
+func (r *OrderRepository) Get(ctx context.Context, id int) (*models.Order, error) {
+	var o models.Order
+	err := scanOrder(r.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = ?", id), &o)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *OrderRepository) ListByUser(ctx context.Context, userID string, role models.OrderRole) ([]models.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE "
+	args := []any{userID}
+	switch role {
+	case models.RoleBuyer:
+		query += "buyer_id = ?"
+	case models.RoleSeller:
+		query += "seller_id = ?"
+	default:
+		query += "(buyer_id = ? OR seller_id = ?)"
+		args = append(args, userID)
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		var o models.Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// Transition は遷移できるかどうかを models.CanTransition の表で判定する
+func (r *OrderRepository) Transition(ctx context.Context, id int, actorID string, to models.OrderStatus) (*models.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var o models.Order
+	err = scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = ? FOR UPDATE", id), &o)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	role := o.RoleOf(actorID)
+	if role == "" {
+		return nil, repository.ErrNotFound
+	}
+	if !models.CanTransition(o.Status, to, role) {
+		return nil, repository.ErrInvalidTransition
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE orders SET status = ? WHERE id = ? AND status = ?", to, o.ID, o.Status)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return nil, repository.ErrConflict
+	}
+
+	if to.Relists() {
+		if err := relist(ctx, tx, &o); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = ?", o.ID), &o); err != nil {
+		return nil, err
+	}
+	return &o, tx.Commit()
+}
+
+func (r *OrderRepository) ExpireUnpaid(ctx context.Context, before time.Time) ([]models.Order, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE status = ? AND created_at < ? FOR UPDATE",
+		models.OrderPendingPayment, before)
+	if err != nil {
+		return nil, err
+	}
+	expired := []models.Order{}
+	for rows.Next() {
+		var o models.Order
+		if err := scanOrder(rows, &o); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range expired {
+		o := &expired[i]
+		if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = ? WHERE id = ?", models.OrderCancelled, o.ID); err != nil {
+			return nil, err
+		}
+		if err := relist(ctx, tx, o); err != nil {
+			return nil, err
+		}
+		o.Status = models.OrderCancelled
+		o.UpdatedAt = now
+	}
+	return expired, tx.Commit()
+}
+
+// relist は注文の商品を再び購入できるようにする。
+// 承諾価格で買った注文なら、その提示も終わった状態にして、出品し直した後に残らないようにする
+func relist(ctx context.Context, tx *sql.Tx, o *models.Order) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET is_sold = FALSE WHERE id = ?", o.ProductID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "UPDATE offers SET status = ? WHERE product_id = ? AND buyer_id = ? AND status = ?",
+		models.OfferCancelled, o.ProductID, o.BuyerID, models.OfferPurchased)
+	return err
+}
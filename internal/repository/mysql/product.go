@@ -0,0 +1,342 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ProductRepository struct {
+	db *sql.DB
+}
+
+// 並び順ごとの ORDER BY と、カーソルより後ろの行を取る条件
+var productOrders = map[repository.ProductSort]struct {
+	orderBy string
+	after   string
+	value   func(p *models.Product) float64
+}{
+	// created_at と id は同じ順に増えるので、新着順は主キーだけで並べる
+	repository.SortNewest: {
+		orderBy: "p.id DESC",
+		after:   "p.id < ?",
+	},
+	repository.SortPriceAsc: {
+		orderBy: "p.price ASC, p.id ASC",
+		after:   "(p.price > ? OR (p.price = ? AND p.id > ?))",
+		value:   func(p *models.Product) float64 { return float64(p.Price) },
+	},
+	repository.SortPriceDesc: {
+		orderBy: "p.price DESC, p.id DESC",
+		after:   "(p.price < ? OR (p.price = ? AND p.id < ?))",
+		value:   func(p *models.Product) float64 { return float64(p.Price) },
+	},
+	repository.SortMostLiked: {
+		orderBy: "p.like_count DESC, p.id DESC",
+		after:   "(p.like_count < ? OR (p.like_count = ? AND p.id < ?))",
+		value:   func(p *models.Product) float64 { return float64(p.LikeCount) },
+	},
+}
+
+// listColumns は一覧で返す列（説明文は含まない）
+const listColumns = "p.id, p.seller_id, p.title, p.price, p.image_url, p.image_key, p.is_sold, p.created_at, p.version, p.is_withdrawn, p.like_count"
+
+func scanListed(row scanner, p *models.Product) error {
+	return row.Scan(&p.ID, &p.SellerID, &p.Title, &p.Price, &p.ImageURL, &p.ImageKey, &p.IsSold, &p.CreatedAt, &p.Version, &p.IsWithdrawn, &p.LikeCount)
+}
+
+func (r *ProductRepository) List(ctx context.Context, q repository.ProductQuery) (*models.ProductPage, error) {
+	order, ok := productOrders[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	cursor, err := repository.DecodeCursor(q.Cursor, string(q.Sort))
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := productFilters(q)
+	if cursor != nil {
+		where = append(where, order.after)
+		if order.value == nil {
+			args = append(args, cursor.ID)
+		} else {
+			args = append(args, cursor.Value, cursor.Value, cursor.ID)
+		}
+	}
+	// 移行前の商品は image_url に Base64 文字列が入っている。
+	// 次のページがあるかを知るために1件多く取る
+	query := "SELECT " + listColumns + " FROM products p WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY " + order.orderBy + " LIMIT ?"
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	for rows.Next() {
+		var p models.Product
+		if err := scanListed(rows, &p); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := &page.Items[q.Limit-1]
+		next := repository.Cursor{Sort: string(q.Sort), ID: last.ID}
+		if order.value != nil {
+			next.Value = order.value(last)
+		}
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
+
+// productFilters は一覧と検索で共通の絞り込み条件を作る
+func productFilters(q repository.ProductQuery) ([]string, []any) {
+	where := []string{"p.deleted_at IS NULL"}
+	var args []any
+	if !q.IncludeWithdrawn {
+		where = append(where, "p.is_withdrawn = FALSE")
+	}
+	if q.MinPrice != nil {
+		where = append(where, "p.price >= ?")
+		args = append(args, *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		where = append(where, "p.price <= ?")
+		args = append(args, *q.MaxPrice)
+	}
+	if q.Sold != nil {
+		where = append(where, "p.is_sold = ?")
+		args = append(args, *q.Sold)
+	}
+	if q.SellerID != "" {
+		where = append(where, "p.seller_id = ?")
+		args = append(args, q.SellerID)
+	}
+	return where, args
+}
+
+func (r *ProductRepository) Search(ctx context.Context, text string, q repository.ProductQuery) (*models.ProductPage, error) {
+	sortKey := string(repository.SortRelevance)
+	cursor, err := repository.DecodeCursor(q.Cursor, sortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	const match = "MATCH(p.title, p.description) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	filters, filterArgs := productFilters(q)
+	where := append([]string{match}, filters...)
+	// 1つ目の ? は SELECT 句のスコア、2つ目は WHERE 句
+	args := append([]any{text, text}, filterArgs...)
+
+	// 関連度は小数なので、カーソルで正確に比較できるよう100万倍して整数にする
+	inner := `SELECT p.id, p.seller_id, p.title, p.description, p.price, p.image_url, p.image_key, p.is_sold, p.created_at, p.version, p.is_withdrawn, p.like_count,
+			CAST(` + match + ` * 1000000 AS SIGNED) AS score
+		FROM products p
+		WHERE ` + strings.Join(where, " AND ")
+
+	query := "SELECT id, seller_id, title, description, price, image_url, image_key, is_sold, created_at, version, is_withdrawn, like_count, score FROM (" + inner + ") t"
+	if cursor != nil {
+		query += " WHERE (t.score < ? OR (t.score = ? AND t.id < ?))"
+		args = append(args, int64(cursor.Value), int64(cursor.Value), cursor.ID)
+	}
+	query += " ORDER BY t.score DESC, t.id DESC LIMIT ?"
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	var scores []int64
+	for rows.Next() {
+		var p models.Product
+		var score int64
+		if err := rows.Scan(&p.ID, &p.SellerID, &p.Title, &p.Description, &p.Price, &p.ImageURL, &p.ImageKey, &p.IsSold, &p.CreatedAt, &p.Version, &p.IsWithdrawn, &p.LikeCount, &score); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, p)
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := page.Items[q.Limit-1]
+		page.NextCursor = repository.Cursor{Sort: sortKey, Value: float64(scores[q.Limit-1]), ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+const productColumns = "id, seller_id, title, description, price, image_url, image_key, is_sold, created_at, version, is_withdrawn, like_count"
+
+func scanProduct(row scanner, p *models.Product) error {
+	return row.Scan(&p.ID, &p.SellerID, &p.Title, &p.Description, &p.Price, &p.ImageURL, &p.ImageKey, &p.IsSold, &p.CreatedAt, &p.Version, &p.IsWithdrawn, &p.LikeCount)
+}
+
+func (r *ProductRepository) Get(ctx context.Context, id int) (*models.Product, error) {
+	var p models.Product
+	err := scanProduct(r.db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = ? AND deleted_at IS NULL", id), &p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProductRepository) Create(ctx context.Context, p *models.Product) error {
+	res, err := r.db.ExecContext(ctx, "INSERT INTO products (seller_id, title, description, price, image_url, image_key) VALUES (?, ?, ?, ?, ?, ?)",
+		p.SellerID, p.Title, p.Description, p.Price, p.ImageURL, p.ImageKey)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = int(id)
+	return r.db.QueryRowContext(ctx, "SELECT created_at, version FROM products WHERE id = ?", id).Scan(&p.CreatedAt, &p.Version)
+}
+
+func (r *ProductRepository) ListBySeller(ctx context.Context, sellerID string, q repository.PageQuery) (*models.ProductPage, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor, repository.CursorSelling)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + listColumns + " FROM products p WHERE p.seller_id = ? AND p.is_withdrawn = FALSE AND p.deleted_at IS NULL"
+	args := []any{sellerID}
+	if cursor != nil {
+		query += " AND p.id < ?"
+		args = append(args, cursor.ID)
+	}
+	query += " ORDER BY p.id DESC LIMIT ?"
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.ProductPage{Items: []models.Product{}}
+	for rows.Next() {
+		var p models.Product
+		if err := scanListed(rows, &p); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = repository.Cursor{Sort: repository.CursorSelling, ID: page.Items[q.Limit-1].ID}.Encode()
+	}
+	return page, nil
+}
+
+// lockOwned は sellerID の商品を FOR UPDATE でロックして読む。売れた商品は ErrSoldOut
+func lockOwned(ctx context.Context, tx *sql.Tx, id int, sellerID string) (*models.Product, error) {
+	var p models.Product
+	err := scanProduct(tx.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id), &p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.SellerID != sellerID {
+		return nil, repository.ErrForbidden
+	}
+	if p.IsSold {
+		return nil, repository.ErrSoldOut
+	}
+	return &p, nil
+}
+
+func (r *ProductRepository) Update(ctx context.Context, id int, sellerID string, version int, patch repository.ProductPatch) (*models.Product, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := lockOwned(ctx, tx, id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Version != version {
+		return nil, repository.ErrConflict
+	}
+	patch.Apply(p)
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE products SET title = ?, description = ?, price = ?, image_url = ?, image_key = ?, version = version + 1 WHERE id = ?",
+		p.Title, p.Description, p.Price, p.ImageURL, p.ImageKey, id)
+	if err != nil {
+		return nil, err
+	}
+	p.Version++
+	return p, tx.Commit()
+}
+
+func (r *ProductRepository) SetWithdrawn(ctx context.Context, id int, sellerID string, withdrawn bool) (*models.Product, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := lockOwned(ctx, tx, id, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsWithdrawn == withdrawn {
+		return p, nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET is_withdrawn = ?, version = version + 1 WHERE id = ?", withdrawn, id); err != nil {
+		return nil, err
+	}
+	p.IsWithdrawn = withdrawn
+	p.Version++
+	return p, tx.Commit()
+}
+
+func (r *ProductRepository) Delete(ctx context.Context, id int, sellerID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOwned(ctx, tx, id, sellerID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET deleted_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
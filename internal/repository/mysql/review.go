@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type ReviewRepository struct {
+	db *sql.DB
+}
+
+// MySQL の重複キーエラーの番号
+const errDuplicateEntry = 1062
+
+func (r *ReviewRepository) Create(ctx context.Context, rv *models.Review) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO reviews (order_id, reviewer_id, reviewee_id, reviewer_role, rating, comment) VALUES (?, ?, ?, ?, ?, ?)",
+		rv.OrderID, rv.ReviewerID, rv.RevieweeID, rv.ReviewerRole, rv.Rating, rv.Comment,
+	)
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == errDuplicateEntry {
+		return repository.ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rv.ID = int(id)
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM reviews WHERE id = ?", id).Scan(&rv.CreatedAt)
+}
+
+func (r *ReviewRepository) ListByReviewee(ctx context.Context, userID string, role models.OrderRole) ([]models.Review, error) {
+	query := `
+		SELECT rv.id, rv.order_id, rv.reviewer_id, rv.reviewee_id, rv.reviewer_role, rv.rating, rv.comment, rv.created_at,
+			COALESCE(u.name, ''), COALESCE(u.avatar_url, '')
+		FROM reviews rv
+		LEFT JOIN users u ON u.id = rv.reviewer_id
+		WHERE rv.reviewee_id = ?`
+	args := []any{userID}
+	if role != "" {
+		query += " AND rv.reviewer_role = ?"
+		args = append(args, role)
+	}
+	query += " ORDER BY rv.id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		var rv models.Review
+		if err := rows.Scan(&rv.ID, &rv.OrderID, &rv.ReviewerID, &rv.RevieweeID, &rv.ReviewerRole, &rv.Rating, &rv.Comment, &rv.CreatedAt,
+			&rv.Reviewer.Name, &rv.Reviewer.AvatarURL); err != nil {
+			return nil, err
+		}
+		rv.Reviewer.ID = rv.ReviewerID
+		reviews = append(reviews, rv)
+	}
+	return reviews, rows.Err()
+}
+
+func (r *ReviewRepository) Summary(ctx context.Context, userID string) (models.RatingSummary, error) {
+	var s models.RatingSummary
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(rating = ?), 0), COALESCE(SUM(rating = ?), 0), COALESCE(SUM(rating = ?), 0), COUNT(*)
+		FROM reviews WHERE reviewee_id = ?`,
+		models.RatingGood, models.RatingNormal, models.RatingBad, userID,
+	).Scan(&s.Good, &s.Normal, &s.Bad, &s.Total)
+	return s, err
+}
@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func (r *UserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	var u models.User
+	err := r.db.QueryRowContext(ctx, "SELECT id, name, email, avatar_url, created_at FROM users WHERE id = ?", id).
+		Scan(&u.ID, &u.Name, &u.Email, &u.AvatarURL, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) Upsert(ctx context.Context, u *models.User) error {
+	// ON DUPLICATE KEY UPDATE を使って、存在しなければ作成、あれば更新
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO users (id, name, email, avatar_url) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE name=?, email=?, avatar_url=?",
+		u.ID, u.Name, u.Email, u.AvatarURL, u.Name, u.Email, u.AvatarURL,
+	)
+	return err
+}
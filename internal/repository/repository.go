@@ -0,0 +1,243 @@
+// Package repository はハンドラーから見たデータアクセスのインターフェースを定義する。
+// 実装は MySQL 用の repository/mysql と、テスト用のインメモリ実装 repository/memory がある
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrSoldOut           = errors.New("product already sold")
+	ErrSelfPurchase      = errors.New("cannot purchase own product")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	ErrConflict          = errors.New("concurrent update")
+	ErrForbidden         = errors.New("not the owner")
+	ErrWithdrawn         = errors.New("product withdrawn")
+	ErrAlreadyExists     = errors.New("already exists")
+	ErrReserved          = errors.New("product reserved for another buyer")
+)
+
+type ProductSort string
+
+const (
+	SortNewest    ProductSort = "newest"
+	SortPriceAsc  ProductSort = "price_asc"
+	SortPriceDesc ProductSort = "price_desc"
+	SortMostLiked ProductSort = "most_liked"
+	// SortRelevance は検索専用の並び順（関連度の高い順）
+	SortRelevance ProductSort = "relevance"
+)
+
+// ProductQuery は商品一覧の絞り込みと並び順。nil / 空文字の条件は使わない
+type ProductQuery struct {
+	Sort     ProductSort
+	Limit    int
+	Cursor   string // 前のページの next_cursor
+	MinPrice *int
+	MaxPrice *int
+	Sold     *bool
+	SellerID string
+	// IncludeWithdrawn が true なら取り下げ中の商品も返す（出品者本人が自分の商品を見るとき用）
+	IncludeWithdrawn bool
+}
+
+// プロフィールの一覧のカーソルの Sort
+const (
+	CursorSelling       = "selling"       // 商品ID
+	CursorLiked         = "liked"         // いいねした日時（Unix秒）と商品ID
+	CursorConversations = "conversations" // 最後のメッセージID
+)
+
+// PageQuery は新しい順に Limit 件ずつ返す一覧の条件
+type PageQuery struct {
+	Limit  int
+	Cursor string // 前のページの next_cursor
+}
+
+// ProductPatch は商品の部分更新。nil の項目は変更しない
+type ProductPatch struct {
+	Title       *string
+	Description *string
+	Price       *int
+	ImageKey    *string
+}
+
+// Apply は p に変更を反映する。画像を差し替えたら移行前の image_url は消す
+func (patch ProductPatch) Apply(p *models.Product) {
+	if patch.Title != nil {
+		p.Title = *patch.Title
+	}
+	if patch.Description != nil {
+		p.Description = *patch.Description
+	}
+	if patch.Price != nil {
+		p.Price = *patch.Price
+	}
+	if patch.ImageKey != nil {
+		p.ImageKey = *patch.ImageKey
+		p.ImageURL = ""
+	}
+}
+
+type ProductRepository interface {
+	// List は商品一覧を1ページ分返す（説明文は含まない）
+	List(ctx context.Context, q ProductQuery) (*models.ProductPage, error)
+	// Search は title と description の全文検索の結果を関連度順に1ページ分返す。
+	// q.Sort は使わない（常に SortRelevance）
+	Search(ctx context.Context, text string, q ProductQuery) (*models.ProductPage, error)
+	// Get は削除済みでない商品を返す。取り下げ中の商品も返す
+	Get(ctx context.Context, id int) (*models.Product, error)
+	// Create は商品を保存し、p.ID と p.CreatedAt を埋める
+	Create(ctx context.Context, p *models.Product) error
+	// ListBySeller は出品中（取り下げ・削除されていない）の商品を新しい順に1ページ分返す（説明文は含まない）
+	ListBySeller(ctx context.Context, sellerID string, q PageQuery) (*models.ProductPage, error)
+
+	// 以下は出品者本人だけが行える操作。商品が無ければ ErrNotFound、
+	// 出品者でなければ ErrForbidden、売れた商品なら ErrSoldOut を返す
+
+	// Update は version が現在の値と一致するときだけ patch を反映する。一致しなければ ErrConflict
+	Update(ctx context.Context, id int, sellerID string, version int, patch ProductPatch) (*models.Product, error)
+	// SetWithdrawn は取り下げ（true）と再出品（false）を切り替える
+	SetWithdrawn(ctx context.Context, id int, sellerID string, withdrawn bool) (*models.Product, error)
+	// Delete は商品を論理削除する
+	Delete(ctx context.Context, id int, sellerID string) error
+}
+
+type UserRepository interface {
+	Get(ctx context.Context, id string) (*models.User, error)
+	// Upsert は存在しなければ作成し、あれば名前とアイコンとメールアドレスを更新する
+	Upsert(ctx context.Context, u *models.User) error
+}
+
+type LikeRepository interface {
+	// Toggle はいいねの登録と解除を切り替え、切り替え後の状態を返す。
+	// 以下の3つとも、商品が無いか削除済みなら ErrNotFound
+	Toggle(ctx context.Context, userID string, productID int) (liked bool, err error)
+	// Like はいいねを登録する。登録済みなら何もせず added は false
+	Like(ctx context.Context, userID string, productID int) (added bool, err error)
+	// Unlike はいいねを解除する。登録されていなければ何もせず removed は false
+	Unlike(ctx context.Context, userID string, productID int) (removed bool, err error)
+	Exists(ctx context.Context, userID string, productID int) (bool, error)
+	// Statuses は productIDs の商品のいいね数と userID がいいねしているかを返す。
+	// 削除済みや存在しない商品は含めない。userID が空なら LikedByMe はすべて false
+	Statuses(ctx context.Context, userID string, productIDs []int) (map[int]models.LikeStatus, error)
+	// ListLikedProducts はいいねした商品を、いいねが新しい順に1ページ分返す。
+	// 公開のプロフィールに載るので、一覧と同じく削除済みと取り下げ中の商品は含めない
+	ListLikedProducts(ctx context.Context, userID string, q PageQuery) (*models.ProductPage, error)
+}
+
+type MessageRepository interface {
+	// Create はメッセージを保存し、m.ID と m.CreatedAt を埋める
+	Create(ctx context.Context, m *models.Message) error
+	// ListThread は商品ごとの2人の間のメッセージを古い順に返す
+	ListThread(ctx context.Context, productID int, user1, user2 string) ([]models.Message, error)
+	// ListThreadAfter は ListThread のうち ID が afterID より大きいものだけを返す（再接続時の取りこぼし用）
+	ListThreadAfter(ctx context.Context, productID int, user1, user2 string, afterID int) ([]models.Message, error)
+}
+
+type ConversationRepository interface {
+	// List は userID が参加している会話を、最後のメッセージが新しい順に1ページ分返す。
+	// q.Limit が0なら全件
+	List(ctx context.Context, userID string, q PageQuery) (*models.ConversationPage, error)
+	// MarkRead は partnerID から届いたメッセージを upToID まで既読にする。
+	// upToID が0なら最新まで。既読位置が戻ることはない
+	MarkRead(ctx context.Context, userID string, productID int, partnerID string, upToID int) error
+}
+
+type ReviewRepository interface {
+	// Create は評価を保存し、r.ID と r.CreatedAt を埋める。
+	// 同じ注文に同じ人が評価済みなら ErrAlreadyExists
+	Create(ctx context.Context, r *models.Review) error
+	// ListByReviewee は userID が受けた評価を新しい順に返す。role を指定すると評価者の立場で絞り込む
+	ListByReviewee(ctx context.Context, userID string, role models.OrderRole) ([]models.Review, error)
+	Summary(ctx context.Context, userID string) (models.RatingSummary, error)
+}
+
+// OfferReply は価格提示への返答
+type OfferReply struct {
+	Action models.OfferAction
+	// Price は counter で提示し返す価格
+	Price int
+	// ExpiresAt は accept なら購入の期限、counter なら新しい提示の返答期限
+	ExpiresAt time.Time
+}
+
+type OfferRepository interface {
+	// Create は買い手の価格提示を保存し、o.ID などを埋める。商品が無ければ ErrNotFound、
+	// 自分の商品なら ErrSelfPurchase、売れていれば ErrSoldOut、取り下げ中なら ErrWithdrawn、
+	// 同じ商品に期限内の返答待ちの提示があれば ErrAlreadyExists
+	Create(ctx context.Context, o *models.Offer) error
+	Get(ctx context.Context, id int) (*models.Offer, error)
+	// ListByProduct は商品の提示を古い順に返す。userID が出品者なら全員分、それ以外なら userID が買い手のものだけ
+	ListByProduct(ctx context.Context, productID int, userID string) ([]models.Offer, error)
+	// Respond は actorID の立場で提示に返答する。当事者でなければ ErrNotFound、
+	// 期限切れなど返答できない状態なら ErrInvalidTransition、その立場で行えない操作なら ErrForbidden。
+	// 承諾時は商品が購入できる状態でなければ ErrSoldOut / ErrWithdrawn、他の買い手の承諾が有効なら ErrReserved。
+	// counter のときは新しい提示を next として返す
+	Respond(ctx context.Context, id int, actorID string, reply OfferReply) (updated, next *models.Offer, err error)
+	// Expire は期限を過ぎた返答待ち・承諾済みの提示を expired にして返す
+	Expire(ctx context.Context) ([]models.Offer, error)
+}
+
+type OrderRepository interface {
+	// Purchase は商品を売り切れにして注文を作る。同時に呼ばれても成功するのは1件だけ。
+	// 承諾済みの価格提示が有効な間は、その買い手だけが承諾価格で購入できる（他の人は ErrReserved）
+	Purchase(ctx context.Context, productID int, buyerID string) (*models.Order, error)
+	Get(ctx context.Context, id int) (*models.Order, error)
+	// ListByUser は role の立場での注文を新しい順に返す。role が空なら両方
+	ListByUser(ctx context.Context, userID string, role models.OrderRole) ([]models.Order, error)
+	// Transition は actorID の立場で注文を to に進める。
+	// 当事者でなければ ErrNotFound、遷移できなければ ErrInvalidTransition
+	Transition(ctx context.Context, id int, actorID string, to models.OrderStatus) (*models.Order, error)
+	// ExpireUnpaid は before より前に作られて支払われていない注文をキャンセルにして返す。
+	// キャンセルと同じく商品は再び購入できるようになる
+	ExpireUnpaid(ctx context.Context, before time.Time) ([]models.Order, error)
+}
+
+// CursorNotifications は通知一覧のカーソルの Sort。ID だけで位置を表す
+const CursorNotifications = "notifications"
+
+// NotificationQuery は通知一覧の条件。新しい順に Limit 件ずつ返す
+type NotificationQuery struct {
+	Limit      int
+	Cursor     string // 前のページの next_cursor
+	UnreadOnly bool
+}
+
+type NotificationRepository interface {
+	// Create は通知を保存し、n.ID と n.CreatedAt を埋める
+	Create(ctx context.Context, n *models.Notification) error
+	// List は1ページ分の通知と、条件に関係なく数えた未読数を返す
+	List(ctx context.Context, userID string, q NotificationQuery) (*models.NotificationPage, error)
+	// MarkRead は ids の通知を既読にする。ids が空ならすべて。他人の通知や既読のものは変えない
+	MarkRead(ctx context.Context, userID string, ids []int) error
+	// Preferences は既定値に保存済みの設定を重ねて返す
+	Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error)
+	// SetPreferences は prefs に含まれる項目だけを保存する
+	SetPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error
+}
+
+type PushSubscriptionRepository interface {
+	// Save は購読を保存する。同じ endpoint があれば持ち主と鍵を置き換える
+	Save(ctx context.Context, s *models.PushSubscription) error
+	Delete(ctx context.Context, userID, endpoint string) error
+	ListByUser(ctx context.Context, userID string) ([]models.PushSubscription, error)
+}
+
+// Repositories はアプリが使うリポジトリ一式
+type Repositories struct {
+	Products          ProductRepository
+	Users             UserRepository
+	Likes             LikeRepository
+	Messages          MessageRepository
+	Conversations     ConversationRepository
+	Orders            OrderRepository
+	Reviews           ReviewRepository
+	Offers            OfferRepository
+	Notifications     NotificationRepository
+	PushSubscriptions PushSubscriptionRepository
+}
@@ -0,0 +1,58 @@
+// Package requestid はリクエストごとのIDを払い出し、レスポンスヘッダーとコンテキストに載せる
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header はリクエストIDをやり取りするヘッダー。呼び出し元が付けてきた値はそのまま使う
+const Header = "X-Request-ID"
+
+type ctxKey struct{}
+
+// Middleware はリクエストIDを決めて、c.Request のコンテキストとレスポンスヘッダーに入れる。
+// ルーター全体とルートグループの両方に付けても、先に決めたIDをそのまま使う
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if FromContext(c.Request.Context()) != "" {
+			c.Next()
+			return
+		}
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = newID()
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, id))
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// FromContext はリクエストIDを返す。Middleware を通っていなければ空文字
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// valid はログやヘッダーにそのまま載せても問題ない値かを確認する
+func valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"backend/internal/metrics"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// AI は出品支援のAI機能。使うモデルは LLM で差し替えられる
+type AI struct {
+	llm LLM
+	log *slog.Logger
+}
+
+// NewAI は llm を使う AI を作る。logger が nil なら slog.Default
+func NewAI(llm LLM, logger *slog.Logger) *AI {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &AI{llm: llm, log: logger}
+}
+
+// 商品説明の自動生成。img は呼び出し側で形式を確かめた画像で、nil なら商品名だけで作る。
+// ctx が切れたら（クライアントの切断やタイムアウト）生成を打ち切る
+func (a *AI) GenerateDescription(ctx context.Context, title string, img *Image) (string, error) {
+	var prompt []Part
+	if img != nil {
+		// 画像をプロンプトに含める
+		prompt = append(prompt, *img)
+	}
+
+	// テキストを追加
+	promptText := fmt.Sprintf("商品名「%s」とこの画像を見て、魅力的な商品説明を100文字程度で作成してください。", title)
+	prompt = append(prompt, Text(promptText))
+
+	start := time.Now()
+	resp, err := a.llm.Generate(ctx, Request{Parts: prompt})
+	if err != nil {
+		observe(metrics.OpDescription, metrics.AIError, start, resp)
+		return "", err
+	}
+	observe(metrics.OpDescription, metrics.AISuccess, start, resp)
+	return resp.Text, nil
+}
+
+// SuggestPrice は価格を査定する。img は GenerateDescription と同じく nil なら画像なしで査定する。
+// 応答がスキーマに合わなければ1回だけやり直し、それでも駄目なら ErrMalformedAIResponse を返す
+func (a *AI) SuggestPrice(ctx context.Context, title string, description string, img *Image) (*PriceSuggestion, error) {
+	var prompt []Part
+	if img != nil {
+		prompt = append(prompt, *img)
+	}
+
+	// プロンプトテキストの作成
+	promptText := fmt.Sprintf(`
+以下の商品名、商品説明、および画像から、日本のフリマアプリでの中古市場価格を査定してください。
+
+商品名：%s
+商品説明：%s
+
+【回答ルール】
+1. 金額はすべて円単位の整数で、price_low <= suggested_price <= price_high とすること。
+2. 画像から判断できる商品の状態（キズや汚れ、付属品など）を condition に反映し、理由を reasons に添えること。
+3. 指定されたJSON形式だけで回答すること。
+`, title, description)
+
+	// テキストをプロンプトに追加
+	prompt = append(prompt, Text(promptText))
+	req := Request{Parts: prompt, Schema: priceSuggestionSchema}
+
+	var lastErr error
+	for attempt := 1; attempt <= 2; attempt++ {
+		start := time.Now()
+		resp, err := a.llm.Generate(ctx, req)
+		if err != nil {
+			// ログは呼び出し側（apierror）でリクエストIDと一緒に残る
+			observe(metrics.OpPrice, metrics.AIError, start, resp)
+			return nil, err
+		}
+		suggestion, err := parsePriceSuggestion(resp.Text)
+		if err == nil {
+			observe(metrics.OpPrice, metrics.AISuccess, start, resp)
+			return suggestion, nil
+		}
+		observe(metrics.OpPrice, metrics.AIMalformed, start, resp)
+		// 応答の本文はユーザーの入力を含むので載せない
+		a.log.WarnContext(ctx, "malformed price suggestion", "attempt", attempt, "error", err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %v", ErrMalformedAIResponse, lastErr)
+}
+
+// observe は LLM の呼び出し1回分をメトリクスに記録する
+func observe(operation, result string, start time.Time, resp Response) {
+	metrics.ObserveAI(operation, result, time.Since(start), resp.Usage.PromptTokens, resp.Usage.OutputTokens)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newTestAI(llm LLM) *AI {
+	return NewAI(llm, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSuggestPrice(t *testing.T) {
+	llm := NewFakeLLM().Script(validSuggestion)
+	s, err := newTestAI(llm).SuggestPrice(context.Background(), "カメラ", "中古", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SuggestedPrice != 3000 {
+		t.Fatalf("SuggestPrice() = %+v", s)
+	}
+	calls := llm.Calls()
+	if len(calls) != 1 || calls[0].Schema != priceSuggestionSchema {
+		t.Fatalf("calls = %+v, want one call with the price schema", calls)
+	}
+}
+
+func TestSuggestPriceRetriesOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies []string
+		wantErr bool
+	}{
+		{"valid after malformed", []string{"not json", validSuggestion}, false},
+		{"valid after out of range", []string{`{"suggested_price":9000,"price_low":1,"price_high":2,"confidence":0.5,"condition":"good","reasons":["x"]}`, validSuggestion}, false},
+		{"malformed twice", []string{"not json", "still not json", validSuggestion}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := NewFakeLLM().Script(tt.replies...)
+			_, err := newTestAI(llm).SuggestPrice(context.Background(), "カメラ", "", nil)
+			if tt.wantErr != errors.Is(err, ErrMalformedAIResponse) {
+				t.Fatalf("SuggestPrice() err = %v, want malformed = %v", err, tt.wantErr)
+			}
+			if n := len(llm.Calls()); n != 2 {
+				t.Fatalf("calls = %d, want 2", n)
+			}
+		})
+	}
+}
+
+func TestSuggestPriceDoesNotRetryLLMErrors(t *testing.T) {
+	boom := errors.New("quota exceeded")
+	llm := NewFakeLLM().ScriptError(boom).Script(validSuggestion)
+	_, err := newTestAI(llm).SuggestPrice(context.Background(), "カメラ", "", nil)
+	if !errors.Is(err, boom) || errors.Is(err, ErrMalformedAIResponse) {
+		t.Fatalf("SuggestPrice() err = %v, want the LLM error", err)
+	}
+	if n := len(llm.Calls()); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+}
+
+func TestGenerateDescription(t *testing.T) {
+	llm := NewFakeLLM().Script("素敵なカメラです")
+	desc, err := newTestAI(llm).GenerateDescription(context.Background(), "カメラ", &Image{MIMEType: "image/png", Data: []byte("\x89PNG")})
+	if err != nil || desc != "素敵なカメラです" {
+		t.Fatalf("GenerateDescription() = %q, %v", desc, err)
+	}
+	// 画像はテキストより前に渡す
+	parts := llm.Calls()[0].Parts
+	img, ok := parts[0].(Image)
+	if len(parts) != 2 || !ok || img.MIMEType != "image/png" {
+		t.Fatalf("parts = %+v", parts)
+	}
+
+	boom := errors.New("unavailable")
+	if _, err := newTestAI(NewFakeLLM().ScriptError(boom)).GenerateDescription(context.Background(), "カメラ", nil); !errors.Is(err, boom) {
+		t.Fatalf("GenerateDescription() err = %v, want %v", err, boom)
+	}
+}
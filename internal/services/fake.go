@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeLLM はテスト用の決まった応答を返す LLM。
+// Script で応答を積んでおくと順番に返し、尽きたらプロンプトのテキストをそのまま返す
+type FakeLLM struct {
+	mu      sync.Mutex
+	script  []fakeReply
+	Handler func(req Request) (Response, error) // 設定されていれば script より優先される
+	calls   []Request
+}
+
+type fakeReply struct {
+	text string
+	err  error
+}
+
+func NewFakeLLM() *FakeLLM {
+	return &FakeLLM{}
+}
+
+// Script は次に返す応答を積む
+func (f *FakeLLM) Script(texts ...string) *FakeLLM {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range texts {
+		f.script = append(f.script, fakeReply{text: t})
+	}
+	return f
+}
+
+// ScriptError は次の呼び出しでエラーを返すようにする
+func (f *FakeLLM) ScriptError(err error) *FakeLLM {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.script = append(f.script, fakeReply{err: err})
+	return f
+}
+
+// Calls はこれまでに受け取ったリクエストを返す
+func (f *FakeLLM) Calls() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.calls...)
+}
+
+func (f *FakeLLM) Generate(ctx context.Context, req Request) (Response, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, req)
+	handler := f.Handler
+	var next *fakeReply
+	if handler == nil && len(f.script) > 0 {
+		next = &f.script[0]
+		f.script = f.script[1:]
+	}
+	f.mu.Unlock()
+
+	switch {
+	case handler != nil:
+		return handler(req)
+	case next != nil && next.err != nil:
+		return Response{}, next.err
+	case next != nil:
+		return Response{Text: next.text}, nil
+	}
+
+	text := ""
+	for _, p := range req.Parts {
+		switch p := p.(type) {
+		case Text:
+			text += string(p)
+		case Image:
+			text += fmt.Sprintf("[image %s %d bytes]", p.MIMEType, len(p.Data))
+		}
+	}
+	return Response{Text: text}, nil
+}
@@ -2,24 +2,18 @@ package services
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
-	"os"
 	"strings"
-	"log"
+	"time"
+
 	"cloud.google.com/go/vertexai/genai"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
-func GetGeminiClient(ctx context.Context) (*genai.Client, error) {
-	// os.Getenv("GCP_PROJECT_ID") が本当に取れているかチェック
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	location := ""
-
-	// 🔴 もし環境変数が空なら、エラーメッセージにそれを混ぜる
-	if projectID == "" {
-		return nil, fmt.Errorf("GCP_PROJECT_ID is empty. Please check Cloud Run env settings")
-	}
+const DefaultGeminiModel = "gemini-2.0-flash-exp"
 
+// GetGeminiClient は Vertex AI のクライアントを作る。location が空なら SDK が環境から推測する
+func GetGeminiClient(ctx context.Context, projectID, location string) (*genai.Client, error) {
 	client, err := genai.NewClient(ctx, projectID, location)
 	if err != nil {
 		return nil, fmt.Errorf("genai.NewClient creation failed: %w", err)
@@ -27,100 +21,112 @@ func GetGeminiClient(ctx context.Context) (*genai.Client, error) {
 	return client, nil
 }
 
-// 商品説明の自動生成
-func GenerateDescription(title string, base64Data string) (string, error) {
-	ctx := context.Background()
-	client, err := GetGeminiClient(ctx)
+// VertexGemini は Vertex AI の Gemini で生成する LLM。クライアントは作ったものを使い回す
+type VertexGemini struct {
+	client  *genai.Client
+	model   string
+	timeout time.Duration
+}
+
+// NewVertexGemini はクライアントを1回だけ作る（認証情報の取得や接続は呼び出しごとにしない）。
+// timeout は1回の生成にかけてよい時間（0 なら呼び出し側の ctx に任せる）
+func NewVertexGemini(ctx context.Context, projectID, location, model string, timeout time.Duration) (*VertexGemini, error) {
+	// クライアントはトークンの更新などで ctx を使い続けるので、キャンセルは引き継がない
+	client, err := GetGeminiClient(context.WithoutCancel(ctx), projectID, location)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer client.Close()
+	return &VertexGemini{client: client, model: model, timeout: timeout}, nil
+}
 
-	model := client.GenerativeModel("gemini-2.0-flash-exp")
+// Close はクライアントの接続を閉じる
+func (g *VertexGemini) Close() error {
+	return g.client.Close()
+}
 
-	// --- 画像データの処理 ---
-	var prompt []genai.Part
+func (g *VertexGemini) Generate(ctx context.Context, req Request) (res Response, err error) {
+	ctx, span := startSpan(ctx, semconv.GenAIProviderNameGCPVertexAI, semconv.GenAIOperationNameGenerateContent, g.model)
+	defer func() { endSpan(span, res, err) }()
 
-	if base64Data != "" {
-		// "data:image/jpeg;base64," などのヘッダーを除去
-		parts := strings.Split(base64Data, ",")
-		rawBase64 := parts[len(parts)-1]
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	model := g.client.GenerativeModel(g.model)
+	if req.Schema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = toGeminiSchema(req.Schema)
+	}
 
-		data, err := base64.StdEncoding.DecodeString(rawBase64)
-		if err == nil {
-			// 画像をプロンプトに含める
-			prompt = append(prompt, genai.ImageData("jpeg", data))
+	prompt := make([]genai.Part, 0, len(req.Parts))
+	for _, p := range req.Parts {
+		switch p := p.(type) {
+		case Text:
+			prompt = append(prompt, genai.Text(p))
+		case Image:
+			prompt = append(prompt, genai.Blob{MIMEType: p.MIMEType, Data: p.Data})
 		}
 	}
 
-	// テキストを追加
-	promptText := fmt.Sprintf("商品名「%s」とこの画像を見て、魅力的な商品説明を100文字程度で作成してください。", title)
-	prompt = append(prompt, genai.Text(promptText))
-
 	resp, err := model.GenerateContent(ctx, prompt...)
 	if err != nil {
-		return "", fmt.Errorf("Gemini生成エラー: %w", err)
+		return Response{}, fmt.Errorf("Gemini生成エラー: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return Response{}, fmt.Errorf("AIからの回答が空でした")
 	}
 
-	if len(resp.Candidates) > 0 {
-		return fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]), nil
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
 	}
-	return "", fmt.Errorf("AIからの回答が空でした")
+	if text.Len() == 0 {
+		return Response{}, fmt.Errorf("AIからの回答が空でした")
+	}
+	out := Response{Text: text.String()}
+	if u := resp.UsageMetadata; u != nil {
+		out.Usage = Usage{PromptTokens: int(u.PromptTokenCount), OutputTokens: int(u.CandidatesTokenCount)}
+	}
+	return out, nil
 }
 
-func SuggestPrice(title string, description string, base64Data string) (string, error) {
-    ctx := context.Background()
-    
-    // 成功している関数と同じ方法でクライアントを取得
-    client, err := GetGeminiClient(ctx) 
-    if err != nil {
-        log.Printf("ERROR: クライアント取得失敗: %v", err)
-        return "", err
-    }
-    defer client.Close()
-
-    model := client.GenerativeModel("gemini-2.0-flash-exp")
-
-    // --- 画像データの処理（GenerateDescriptionの成功パターンに合わせる） ---
-    var prompt []genai.Part
-
-    if base64Data != "" {
-        parts := strings.Split(base64Data, ",")
-        rawBase64 := parts[len(parts)-1]
-        data, err := base64.StdEncoding.DecodeString(rawBase64)
-        if err == nil {
-            prompt = append(prompt, genai.ImageData("jpeg", data))
-        } else {
-            log.Printf("ERROR: 画像デコード失敗: %v", err)
-        }
-    }
-
-    // プロンプトテキストの作成
-    promptText := fmt.Sprintf(`
-以下の商品名、商品説明、および画像から、日本のフリマアプリでの中古市場価格を査定してください。
-
-商品名：%s
-商品説明：%s
-
-【回答ルール】
-1. 査定金額は **〇〇円** と太字で表記すること。
-2. 画像から判断できる商品の状態（キズや汚れ、付属品など）を考慮して理由を添えること。
-3. 簡潔に回答してください。
-`, title, description)
-
-    // テキストをプロンプトに追加
-    prompt = append(prompt, genai.Text(promptText))
-
-    // 実行
-    resp, err := model.GenerateContent(ctx, prompt...)
-    if err != nil {
-        log.Printf("ERROR: Gemini生成失敗: %v", err)
-        return "", fmt.Errorf("Gemini生成エラー: %w", err)
-    }
+var geminiTypes = map[string]genai.Type{
+	"object":  genai.TypeObject,
+	"array":   genai.TypeArray,
+	"string":  genai.TypeString,
+	"integer": genai.TypeInteger,
+	"number":  genai.TypeNumber,
+	"boolean": genai.TypeBoolean,
+}
 
-    if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
-        return fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]), nil
-    }
-    
-    return "", fmt.Errorf("AIからの回答が空でした")
-}
\ No newline at end of file
+func toGeminiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	gs := &genai.Schema{
+		Type:        geminiTypes[s.Type],
+		Description: s.Description,
+		Enum:        s.Enum,
+		Items:       toGeminiSchema(s.Items),
+		Required:    s.Required,
+	}
+	if len(s.Enum) > 0 {
+		gs.Format = "enum"
+	}
+	if s.Minimum != nil {
+		gs.Minimum = *s.Minimum
+	}
+	if s.Maximum != nil {
+		gs.Maximum = *s.Maximum
+	}
+	if len(s.Properties) > 0 {
+		gs.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			gs.Properties[name] = toGeminiSchema(prop)
+		}
+	}
+	return gs
+}
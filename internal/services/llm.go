@@ -0,0 +1,79 @@
+package services
+
+import (
+	"backend/internal/config"
+	"context"
+	"fmt"
+)
+
+// Part はプロンプトの部品（テキストまたは画像）
+type Part interface {
+	isPart()
+}
+
+// Text はテキストのプロンプト
+type Text string
+
+// Image は画像のプロンプト
+type Image struct {
+	MIMEType string // 例: "image/jpeg"
+	Data     []byte
+}
+
+func (Text) isPart()  {}
+func (Image) isPart() {}
+
+type Request struct {
+	Parts []Part
+	// Schema が設定されていると、この形の JSON だけを返すようモデルに指示する
+	// （Gemini の responseSchema、OpenAI の response_format）
+	Schema *Schema
+}
+
+// Schema は応答JSONの形。OpenAPI のサブセットで、Gemini と OpenAI の両方に変換できる範囲だけ持つ
+type Schema struct {
+	Type        string // "object", "array", "string", "integer", "number", "boolean"
+	Description string
+	Enum        []string
+	Items       *Schema
+	Properties  map[string]*Schema
+	Required    []string
+	Minimum     *float64
+	Maximum     *float64
+}
+
+type Response struct {
+	Text  string
+	Usage Usage
+}
+
+// Usage は1回の生成で使ったトークン数。プロバイダが返さなければ 0
+type Usage struct {
+	PromptTokens int
+	OutputTokens int
+}
+
+// LLM はテキストと画像からテキストを生成するモデル。
+// Vertex AI の Gemini、OpenAI 互換のHTTPエンドポイント、テスト用の Fake がある
+type LLM interface {
+	Generate(ctx context.Context, req Request) (Response, error)
+}
+
+// NewLLM は cfg.Provider (vertex|openai|fake) のプロバイダを作る。
+// 設定は config.AI.Validate で確かめてある前提。io.Closer を実装するものは停止時に閉じる
+func NewLLM(ctx context.Context, cfg config.AI) (LLM, error) {
+	switch cfg.Provider {
+	case "vertex":
+		model := cfg.Model
+		if model == "" {
+			model = DefaultGeminiModel
+		}
+		return NewVertexGemini(ctx, cfg.ProjectID, cfg.Location, model, cfg.Timeout)
+	case "openai":
+		return NewOpenAICompatible(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.Model, cfg.Timeout), nil
+	case "fake":
+		return NewFakeLLM(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+}
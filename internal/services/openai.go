@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// OpenAICompatible は OpenAI 互換の /chat/completions を叩く LLM。
+// Ollama や vLLM、LM Studio などローカルのエンドポイントで動かすのに使う。
+// timeout は1回の生成（応答の読み込みまで）にかけてよい時間
+type OpenAICompatible struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewOpenAICompatible(baseURL, apiKey, model string, timeout time.Duration) *OpenAICompatible {
+	return &OpenAICompatible{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type chatContent struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"`
+}
+
+type chatMessage struct {
+	Role    string        `json:"role"`
+	Content []chatContent `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string         `json:"name"`
+		Schema map[string]any `json:"schema"`
+	} `json:"json_schema"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (o *OpenAICompatible) Generate(ctx context.Context, req Request) (res Response, err error) {
+	ctx, span := startSpan(ctx, semconv.GenAIProviderNameOpenAI, semconv.GenAIOperationNameChat, o.model)
+	defer func() { endSpan(span, res, err) }()
+
+	msg := chatMessage{Role: "user"}
+	for _, p := range req.Parts {
+		switch p := p.(type) {
+		case Text:
+			msg.Content = append(msg.Content, chatContent{Type: "text", Text: string(p)})
+		case Image:
+			url := "data:" + p.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+			msg.Content = append(msg.Content, chatContent{Type: "image_url", ImageURL: &chatImageURL{URL: url}})
+		}
+	}
+
+	chatReq := chatRequest{Model: o.model, Messages: []chatMessage{msg}}
+	if req.Schema != nil {
+		chatReq.ResponseFormat = &responseFormat{Type: "json_schema"}
+		chatReq.ResponseFormat.JSONSchema.Name = "response"
+		chatReq.ResponseFormat.JSONSchema.Schema = toJSONSchema(req.Schema)
+	}
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		return Response{}, fmt.Errorf("LLMへのリクエストに失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+	var out chatResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Response{}, fmt.Errorf("LLMの応答を解析できません (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if out.Error != nil {
+			return Response{}, fmt.Errorf("LLMエラー (status %d): %s", resp.StatusCode, out.Error.Message)
+		}
+		return Response{}, fmt.Errorf("LLMエラー (status %d)", resp.StatusCode)
+	}
+	if len(out.Choices) == 0 || out.Choices[0].Message.Content == "" {
+		return Response{}, fmt.Errorf("AIからの回答が空でした")
+	}
+	return Response{
+		Text:  out.Choices[0].Message.Content,
+		Usage: Usage{PromptTokens: out.Usage.PromptTokens, OutputTokens: out.Usage.CompletionTokens},
+	}, nil
+}
+
+// toJSONSchema は Schema を JSON Schema の map にする
+func toJSONSchema(s *Schema) map[string]any {
+	js := map[string]any{"type": s.Type}
+	if s.Description != "" {
+		js["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		js["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		js["items"] = toJSONSchema(s.Items)
+	}
+	if s.Minimum != nil {
+		js["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		js["maximum"] = *s.Maximum
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = toJSONSchema(prop)
+		}
+		js["properties"] = props
+		js["required"] = s.Required
+	}
+	return js
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedAIResponse はモデルの応答が期待したJSONにならなかったことを表す
+var ErrMalformedAIResponse = errors.New("AIの応答が不正な形式でした")
+
+// 商品の状態（フリマアプリの一般的な6段階）
+var itemConditions = []string{
+	"new",      // 新品、未使用
+	"like_new", // 未使用に近い
+	"good",     // 目立った傷や汚れなし
+	"fair",     // やや傷や汚れあり
+	"poor",     // 傷や汚れあり
+	"bad",      // 全体的に状態が悪い
+}
+
+// PriceSuggestion はAIによる価格査定の結果
+type PriceSuggestion struct {
+	SuggestedPrice int      `json:"suggested_price"`
+	PriceLow       int      `json:"price_low"`
+	PriceHigh      int      `json:"price_high"`
+	Confidence     float64  `json:"confidence"` // 0〜1
+	Condition      string   `json:"condition"`
+	Reasons        []string `json:"reasons"`
+}
+
+func float(v float64) *float64 { return &v }
+
+var priceSuggestionSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"suggested_price": {Type: "integer", Description: "査定金額（円）", Minimum: float(1)},
+		"price_low":       {Type: "integer", Description: "相場の下限（円）", Minimum: float(1)},
+		"price_high":      {Type: "integer", Description: "相場の上限（円）", Minimum: float(1)},
+		"confidence":      {Type: "number", Description: "査定の確信度（0〜1）", Minimum: float(0), Maximum: float(1)},
+		"condition":       {Type: "string", Description: "画像と説明から判断した商品の状態", Enum: itemConditions},
+		"reasons":         {Type: "array", Description: "査定の理由（日本語で簡潔に）", Items: &Schema{Type: "string"}},
+	},
+	Required: []string{"suggested_price", "price_low", "price_high", "confidence", "condition", "reasons"},
+}
+
+// parsePriceSuggestion はモデルの応答をパースして中身を検証する
+func parsePriceSuggestion(text string) (*PriceSuggestion, error) {
+	// スキーマ指定に対応していないモデルはコードブロックで囲んで返すことがある
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	var s PriceSuggestion
+	if err := json.Unmarshal([]byte(text), &s); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *PriceSuggestion) validate() error {
+	switch {
+	case s.PriceLow <= 0 || s.SuggestedPrice <= 0 || s.PriceHigh <= 0:
+		return errors.New("prices must be positive")
+	case s.PriceLow > s.SuggestedPrice || s.SuggestedPrice > s.PriceHigh:
+		return fmt.Errorf("price range is inconsistent: %d <= %d <= %d", s.PriceLow, s.SuggestedPrice, s.PriceHigh)
+	case s.Confidence < 0 || s.Confidence > 1:
+		return fmt.Errorf("confidence out of range: %v", s.Confidence)
+	case !contains(itemConditions, s.Condition):
+		return fmt.Errorf("unknown condition %q", s.Condition)
+	case len(s.Reasons) == 0:
+		return errors.New("reasons are empty")
+	}
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const validSuggestion = `{"suggested_price":3000,"price_low":2500,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["目立った傷なし"]}`
+
+func TestParsePriceSuggestion(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"valid", validSuggestion, ""},
+		{"code block", "```json\n" + validSuggestion + "\n```", ""},
+		{"not json", "3000円くらいです", "decode"},
+		{"truncated", validSuggestion[:40], "decode"},
+		{"wrong type", `{"suggested_price":"3000","price_low":2500,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["x"]}`, "decode"},
+		{"zero price", `{"suggested_price":0,"price_low":0,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["x"]}`, "positive"},
+		{"negative price", `{"suggested_price":3000,"price_low":-1,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["x"]}`, "positive"},
+		{"suggested above range", `{"suggested_price":4000,"price_low":2500,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["x"]}`, "inconsistent"},
+		{"low above high", `{"suggested_price":3000,"price_low":3600,"price_high":3500,"confidence":0.8,"condition":"good","reasons":["x"]}`, "inconsistent"},
+		{"confidence above 1", `{"suggested_price":3000,"price_low":2500,"price_high":3500,"confidence":1.5,"condition":"good","reasons":["x"]}`, "confidence"},
+		{"negative confidence", `{"suggested_price":3000,"price_low":2500,"price_high":3500,"confidence":-0.1,"condition":"good","reasons":["x"]}`, "confidence"},
+		{"unknown condition", `{"suggested_price":3000,"price_low":2500,"price_high":3500,"confidence":0.8,"condition":"mint","reasons":["x"]}`, "condition"},
+		{"no reasons", `{"suggested_price":3000,"price_low":2500,"price_high":3500,"confidence":0.8,"condition":"good","reasons":[]}`, "reasons"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parsePriceSuggestion(tt.text)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("parsePriceSuggestion() err = %v", err)
+				}
+				if s.SuggestedPrice != 3000 || s.Condition != "good" {
+					t.Fatalf("parsePriceSuggestion() = %+v", s)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("parsePriceSuggestion() err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("backend/internal/services")
+
+// startSpan はモデル呼び出し1回分のスパンを始める（名前は GenAI のセマンティック規約に合わせる）
+func startSpan(ctx context.Context, provider, operation attribute.KeyValue, model string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation.Value.AsString()+" "+model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(provider, operation, semconv.GenAIRequestModel(model)),
+	)
+}
+
+// endSpan は結果（トークン数またはエラー）を記録してスパンを閉じる
+func endSpan(span trace.Span, resp Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(
+			semconv.GenAIUsageInputTokens(resp.Usage.PromptTokens),
+			semconv.GenAIUsageOutputTokens(resp.Usage.OutputTokens),
+		)
+	}
+	span.End()
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// GCS は Cloud Storage のバケットに保存する。
+// endpoint を指定すると fake-gcs-server などの互換サーバーに（認証なしで）接続する
+type GCS struct {
+	bucket    *gcs.BucketHandle
+	publicURL string
+}
+
+func NewGCS(ctx context.Context, bucket, endpoint, publicURL string) (*GCS, error) {
+	var opts []option.ClientOption
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint), option.WithoutAuthentication())
+	}
+	client, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create gcs client: %w", err)
+	}
+	return &GCS{bucket: client.Bucket(bucket), publicURL: publicURL}, nil
+}
+
+func (g *GCS) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	w := g.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	// キーは毎回ランダムに作るので中身が変わることはない
+	w.CacheControl = "public, max-age=31536000, immutable"
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (g *GCS) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	if !ValidKey(key) {
+		return nil, "", ErrNotFound
+	}
+	r, err := g.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return r, r.Attrs.ContentType, nil
+}
+
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	err := g.bucket.Object(key).Delete(ctx)
+	if err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (g *GCS) URL(key string) string {
+	return joinURL(g.publicURL, key)
+}
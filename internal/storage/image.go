@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MaxImageSize は商品画像1枚の上限サイズ
+const MaxImageSize = 5 << 20
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type")
+	ErrImageTooLarge    = errors.New("image too large")
+)
+
+// 受け付ける画像形式と拡張子
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// DetectImage は中身から画像形式を判定する（クライアントが申告する Content-Type は信用しない）
+func DetectImage(data []byte) (contentType, ext string, err error) {
+	if len(data) > MaxImageSize {
+		return "", "", ErrImageTooLarge
+	}
+	contentType = http.DetectContentType(data)
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedImage
+	}
+	return contentType, ext, nil
+}
+
+// ProductImagePrefix は uid がアップロードした商品画像のキーの前半 (products/<uid>)。
+// 出品や編集で受け取った image_key が本人のアップロードしたものか確かめるのに使う
+func ProductImagePrefix(uid string) string {
+	return "products/" + url.PathEscape(uid)
+}
+
+// NewImageKey は prefix の下に推測されにくいランダムなキーを作る
+func NewImageKey(prefix, ext string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return prefix + "/" + hex.EncodeToString(b) + ext
+}
+
+// DecodeDataURL は "data:image/jpeg;base64,..." 形式の文字列をデコードする。
+// MaxImageSize を超える画像はデコードする前に ErrImageTooLarge を返す
+func DecodeDataURL(s string) ([]byte, error) {
+	_, raw, found := strings.Cut(s, ",")
+	if !found {
+		return nil, errors.New("not a data URL")
+	}
+	if len(raw) > base64.StdEncoding.EncodedLen(MaxImageSize) {
+		return nil, ErrImageTooLarge
+	}
+	return base64.StdEncoding.DecodeString(raw)
+}
@@ -0,0 +1,54 @@
+// Package storage は商品画像などのファイルを保存する。
+// ローカルファイルシステムと GCS（および fake-gcs-server などの互換サーバー）の実装がある
+package storage
+
+import (
+	"backend/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type Storage interface {
+	// Put は r の内容を key に保存する
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Open は key の内容と Content-Type を返す。無ければ ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, string, error)
+	Delete(ctx context.Context, key string) error
+	// URL は key をブラウザから取得するためのURL
+	URL(key string) string
+}
+
+// New は cfg.Backend (local|gcs) の実装を作る。
+// 公開URLの前半は cfg.PublicURL（既定は画像配信API /api/images）
+func New(ctx context.Context, cfg config.Storage) (Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocal(cfg.LocalDir, cfg.PublicURL)
+	case "gcs":
+		return NewGCS(ctx, cfg.GCSBucket, cfg.GCSEndpoint, cfg.PublicURL)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
+
+// ValidKey はパストラバーサルになりうるキーを弾く。クライアントから受け取ったキーの確認にも使う
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return false
+		}
+	}
+	return true
+}